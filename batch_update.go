@@ -0,0 +1,220 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// 按 keyCols 批量更新多行数据，每行的值不同
+type BatchUpdateContext struct {
+	err      error
+	table    string
+	setCols  []string
+	keyCols  []string
+	rows     int
+	params   []interface{} // 按行排列，每行依次为 setCols, keyCols 的值
+	caseWhen bool
+	db       *sql.DB
+	tx       *sql.Tx
+	dialect  Dialect
+	ctx      context.Context
+}
+
+func createBatchUpdateContext(ctx context.Context, db *sql.DB, tx *sql.Tx, dialect Dialect, table string, setCols, keyCols []string, source interface{}) *BatchUpdateContext {
+	if len(setCols) == 0 {
+		return &BatchUpdateContext{err: ErrNoSetColumns}
+	}
+	if len(keyCols) == 0 {
+		return &BatchUpdateContext{err: fmt.Errorf("%w. batch update requires [keyCols] parameter", ErrMissingWhere)}
+	}
+	dataset := interfaceToArray(source)
+	if len(dataset) == 0 {
+		return &BatchUpdateContext{}
+	}
+	cols := make([]string, 0, len(setCols)+len(keyCols))
+	cols = append(append(cols, setCols...), keyCols...)
+	params, err := ReadValue(cols, FieldMapping(dataset[0]), dataset...)
+	if err != nil {
+		return &BatchUpdateContext{err: err}
+	}
+	return &BatchUpdateContext{table: table, setCols: setCols, keyCols: keyCols, rows: len(dataset), params: params, db: db, tx: tx, dialect: dialect, ctx: ctx}
+}
+
+func (a *BatchUpdateContext) WithContext(ctx context.Context) *BatchUpdateContext {
+	a.ctx = ctx
+	return a
+}
+
+// 使用单条语句更新所有行，如 update t set a = case id when ? then ? ... else a end where id in (...)
+// 参数个数超过方言的限制时拆分为多条语句
+// 默认在事务中通过同一个预编译语句逐行更新
+func (a *BatchUpdateContext) CaseWhen() *BatchUpdateContext {
+	a.caseWhen = true
+	return a
+}
+
+// 返回更新的行数，在事务中执行，任意一行失败时全部回滚
+// 在 TransactionContext 中使用当前事务
+func (a *BatchUpdateContext) Exec() (int64, error) {
+	if a.err != nil { // 如果构建异常，不执行
+		return 0, a.err
+	}
+	if a.rows == 0 { // 无数据，不需要执行
+		return 0, nil
+	}
+	ctx := contextOf(a.ctx)
+	if a.tx != nil {
+		return a.exec(ctx, a.tx)
+	}
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, wrapDBError(err)
+	}
+	n, err := a.exec(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, wrapDBError(err)
+	}
+	return n, nil
+}
+
+func (a *BatchUpdateContext) exec(ctx context.Context, tx *sql.Tx) (int64, error) {
+	width := len(a.setCols) + len(a.keyCols)
+	var total int64
+	if a.caseWhen {
+		size := a.batchRows()
+		for start := 0; start < a.rows; start += size {
+			end := start + size
+			if end > a.rows {
+				end = a.rows
+			}
+			query, params := a.caseStatement(a.params[start*width : end*width])
+			n, err := execute(ctx, nil, tx, a.dialect, query, params...)
+			if err != nil {
+				return 0, err
+			}
+			total += n
+		}
+		return total, nil
+	}
+	stat, err := tx.PrepareContext(ctx, rebind(a.dialect, a.rowStatement()))
+	if err != nil {
+		return 0, wrapDBError(err)
+	}
+	defer stat.Close()
+	for i := 0; i < a.rows; i++ {
+		rs, err := stat.ExecContext(ctx, a.params[i*width:(i+1)*width]...)
+		if err != nil {
+			return 0, wrapDBError(err)
+		}
+		n, err := rs.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// 逐行更新的语句
+func (a *BatchUpdateContext) rowStatement() string {
+	sets := make([]string, 0, len(a.setCols))
+	for _, c := range a.setCols {
+		sets = append(sets, quoteIdentifier(a.dialect, c)+" = ?")
+	}
+	return "update " + a.table + " set " + strings.Join(sets, ",") + " where " + a.keyCondition()
+}
+
+// k1 = ? and k2 = ?
+func (a *BatchUpdateContext) keyCondition() string {
+	conds := make([]string, 0, len(a.keyCols))
+	for _, k := range a.keyCols {
+		conds = append(conds, quoteIdentifier(a.dialect, k)+" = ?")
+	}
+	return strings.Join(conds, " and ")
+}
+
+// 使用 case when 更新 params 中所有行的语句及参数
+// 单个 key 时为 a = case id when ? then ? ... else a end where id in (?, ...)
+// 多个 key 时为 a = case when k1 = ? and k2 = ? then ? ... else a end where (k1 = ? and k2 = ?) or ...
+func (a *BatchUpdateContext) caseStatement(params []interface{}) (string, []interface{}) {
+	width := len(a.setCols) + len(a.keyCols)
+	rows := len(params) / width
+	single := len(a.keyCols) == 1
+	cond := a.keyCondition()
+	key := quoteIdentifier(a.dialect, a.keyCols[0])
+	rs := make([]interface{}, 0, rows*(len(a.setCols)*(len(a.keyCols)+1)+len(a.keyCols)))
+	var sb strings.Builder
+	sb.WriteString("update " + a.table + " set ")
+	for i, c := range a.setCols {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		c = quoteIdentifier(a.dialect, c)
+		if single {
+			sb.WriteString(c + " = case " + key)
+		} else {
+			sb.WriteString(c + " = case")
+		}
+		for r := 0; r < rows; r++ {
+			row := params[r*width : (r+1)*width]
+			if single {
+				sb.WriteString(" when ? then ?")
+			} else {
+				sb.WriteString(" when " + cond + " then ?")
+			}
+			rs = append(rs, row[len(a.setCols):]...)
+			rs = append(rs, row[i])
+		}
+		sb.WriteString(" else " + c + " end")
+	}
+	sb.WriteString(" where ")
+	if single {
+		sb.WriteString(key + " in (" + placeholder(rows) + ")")
+	}
+	for r := 0; r < rows; r++ {
+		if !single {
+			if r > 0 {
+				sb.WriteString(" or ")
+			}
+			sb.WriteString("(" + cond + ")")
+		}
+		rs = append(rs, params[r*width+len(a.setCols):(r+1)*width]...)
+	}
+	return sb.String(), rs
+}
+
+// case when 模式下每条语句的行数
+func (a *BatchUpdateContext) batchRows() int {
+	size := a.rows
+	perRow := len(a.setCols)*(len(a.keyCols)+1) + len(a.keyCols)
+	if a.dialect != nil && a.dialect.MaxParams() > 0 && size*perRow > a.dialect.MaxParams() {
+		size = a.dialect.MaxParams() / perRow
+	}
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// 返回 BatchUpdateContext 构建过程中的异常
+func (a *BatchUpdateContext) ContextError() error {
+	return a.err
+}
+
+// 返回语句和参数，case when 模式下为未拆分的语句，否则为逐行更新的语句及按行排列的参数
+func (a *BatchUpdateContext) Desc() (string, []interface{}) {
+	if a.rows == 0 {
+		return "", a.params
+	}
+	if a.caseWhen {
+		query, params := a.caseStatement(a.params)
+		return rebind(a.dialect, query), params
+	}
+	return rebind(a.dialect, a.rowStatement()), a.params
+}
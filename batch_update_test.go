@@ -0,0 +1,80 @@
+package orm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCaseStatement(t *testing.T) {
+	ps := []testPerson{{ID: 1, Name: "a", Age: 10}, {ID: 2, Name: "b", Age: 20}}
+	u := createBatchUpdateContext(nil, nil, nil, MySQL, "person", []string{"name", "age"}, []string{"id"}, ps).CaseWhen()
+	sql, params := u.Desc()
+	want := "update person set `name` = case `id` when ? then ? when ? then ? else `name` end," +
+		"`age` = case `id` when ? then ? when ? then ? else `age` end where `id` in (?,?)"
+	if sql != want {
+		t.Errorf("got  %s\nwant %s", sql, want)
+	}
+	wantParams := []interface{}{int64(1), "a", int64(2), "b", int64(1), 10, int64(2), 20, int64(1), int64(2)}
+	if !reflect.DeepEqual(params, wantParams) {
+		t.Errorf("params = %v, want %v", params, wantParams)
+	}
+
+	u = createBatchUpdateContext(nil, nil, nil, PostgreSQL, "person", []string{"age"}, []string{"id", "name"}, ps).CaseWhen()
+	sql, params = u.Desc()
+	want = `update person set "age" = case when "id" = $1 and "name" = $2 then $3 when "id" = $4 and "name" = $5 then $6 else "age" end ` +
+		`where ("id" = $7 and "name" = $8) or ("id" = $9 and "name" = $10)`
+	if sql != want {
+		t.Errorf("got  %s\nwant %s", sql, want)
+	}
+	wantParams = []interface{}{int64(1), "a", 10, int64(2), "b", 20, int64(1), "a", int64(2), "b"}
+	if !reflect.DeepEqual(params, wantParams) {
+		t.Errorf("params = %v, want %v", params, wantParams)
+	}
+}
+
+func TestBatchUpdateRowStatement(t *testing.T) {
+	ps := []testPerson{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}
+	sql, params := createBatchUpdateContext(nil, nil, nil, SQLite, "person", []string{"name"}, []string{"id"}, ps).Desc()
+	if want := `update person set "name" = ? where "id" = ?`; sql != want {
+		t.Errorf("got %s, want %s", sql, want)
+	}
+	if !reflect.DeepEqual(params, []interface{}{"a", int64(1), "b", int64(2)}) {
+		t.Errorf("params = %v", params)
+	}
+}
+
+func TestBatchUpdateBatchRows(t *testing.T) {
+	ps := make([]testPerson, 40000)
+	u := createBatchUpdateContext(nil, nil, nil, SQLite, "person", []string{"name", "age"}, []string{"id"}, ps)
+	// 每行 2*(1+1)+1 = 5 个参数
+	if got := u.batchRows(); got != 32766/5 {
+		t.Errorf("batchRows() = %d, want %d", got, 32766/5)
+	}
+	u = createBatchUpdateContext(nil, nil, nil, SQLite, "person", []string{"name"}, []string{"id"}, ps[:10])
+	if got := u.batchRows(); got != 10 {
+		t.Errorf("batchRows() = %d, want 10", got)
+	}
+}
+
+func TestUpdateBatch(t *testing.T) {
+	c := openTestContext(t)
+	ps := []testPerson{{Name: "a", Age: 1}, {Name: "b", Age: 2}, {Name: "c", Age: 3}}
+	if _, err := c.Insert("person", []string{"name", "age"}, ps).FillPK().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	ps[0].Name, ps[1].Age = "aa", 20
+	if n, err := c.UpdateBatch("person", []string{"name", "age"}, []string{"id"}, ps).Exec(); err != nil || n != 3 {
+		t.Fatalf("Exec() = %d, %v", n, err)
+	}
+	ps[2].Name, ps[2].Age = "cc", 30
+	if n, err := c.UpdateBatch("person", []string{"name", "age"}, []string{"id"}, ps).CaseWhen().Exec(); err != nil || n != 3 {
+		t.Fatalf("CaseWhen Exec() = %d, %v", n, err)
+	}
+	var rs []testPerson
+	if err := c.Select("person", []string{"id", "name", "age"}, "").OrderByAsc("id").Result(&rs); err != nil {
+		t.Fatal(err)
+	}
+	if rs[0].Name != "aa" || rs[1].Age != 20 || rs[2].Name != "cc" || rs[2].Age != 30 {
+		t.Fatalf("rows = %+v", rs)
+	}
+}
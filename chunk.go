@@ -0,0 +1,180 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// 按 key 升序分批查询，每批最多 size 行，通过 where key > 上一批最后的 key 翻页，避免 limit offset 过大时的性能问题
+// fn 的类型为 func([]T) error 或 func([]*T) error，T 为结构体时必须包含 key 对应的字段，否则 T 即为 key 的值
+// fn 返回 ErrStop 时停止查询并返回 nil，返回其他异常时停止查询并返回该异常
+// key 必须在查询的字段中且值唯一，不能与 GroupBy/OrderBy/Limit 以及 Search 同时使用
+//
+//	err := ctx.Select("person", cols, "user_age > ?", 18).Chunk("id", 1000, func(ps []*Person) error {
+//		return w.Write(ps)
+//	})
+func (a *SelectContext) Chunk(key string, size int, fn interface{}) error {
+	if a.err != nil { // 如果构建异常，不执行
+		return a.err
+	}
+	if a.advanced {
+		return errors.New("can not use Chunk when build by Search")
+	}
+	if a.step > 1 {
+		return errors.New("can not use Chunk with GroupBy, OrderBy or Limit")
+	}
+	if size <= 0 {
+		return errors.New("chunk size must be greater than 0")
+	}
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func || fv.Type().NumIn() != 1 || fv.Type().NumOut() != 1 || fv.Type().Out(0) != type_error || fv.Type().In(0).Kind() != reflect.Slice {
+		return errors.New("fn must be func([]T) error or func([]*T) error")
+	}
+	batchType := fv.Type().In(0)
+	keyOf, err := chunkKey(batchType.Elem(), key)
+	if err != nil {
+		return err
+	}
+	var last interface{}
+	for {
+		q := *a
+		q.sql, q.params = a.chunkSQL(key, last, size)
+		batch := reflect.New(batchType)
+		if err := q.chunkBatch(batch.Interface(), key); err != nil {
+			return err
+		}
+		n := batch.Elem().Len()
+		if n == 0 {
+			return nil
+		}
+		next, err := keyOf(batch.Elem().Index(n - 1))
+		if err != nil {
+			return err
+		}
+		if next == nil {
+			return errors.New("chunk key " + key + " is null")
+		}
+		if last != nil && !keyAdvanced(last, next) { // 避免 key 未正确读取时重复查询同一批数据
+			return fmt.Errorf("chunk key %s does not increase: %v -> %v", key, last, next)
+		}
+		last = next
+		if err, _ := fv.Call([]reflect.Value{batch.Elem()})[0].Interface().(error); err != nil {
+			if err == ErrStop {
+				return nil
+			}
+			return err
+		}
+		if n < size {
+			return nil
+		}
+	}
+}
+
+// 查询一批数据追加到 batch 中，key 必须在查询结果中
+func (a *SelectContext) chunkBatch(batch interface{}, key string) error {
+	rows, err := a.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	column := chunkColumn(key)
+	selected := false
+	for _, c := range rows.Columns() {
+		if strings.EqualFold(c, column) {
+			selected = true
+			break
+		}
+	}
+	if !selected {
+		return fmt.Errorf("%w: chunk key %s is not selected", ErrUnknownColumn, key)
+	}
+	for rows.Next() {
+		if err := rows.Scan(batch); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// next 是否大于 last，无法比较大小的类型只判断是否相等
+func keyAdvanced(last, next interface{}) bool {
+	lv, nv := reflect.ValueOf(last), reflect.ValueOf(next)
+	if lv.Type() != nv.Type() {
+		return !reflect.DeepEqual(last, next)
+	}
+	switch lv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return nv.Int() > lv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return nv.Uint() > lv.Uint()
+	case reflect.Float32, reflect.Float64:
+		return nv.Float() > lv.Float()
+	case reflect.String:
+		return nv.String() != lv.String() // 字符串的排序规则由数据库决定
+	}
+	if t, ok := next.(time.Time); ok {
+		return t.After(last.(time.Time))
+	}
+	return !reflect.DeepEqual(last, next)
+}
+
+// key 对应的 column，去掉表别名和引号
+func chunkColumn(key string) string {
+	column := key
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		column = column[i+1:]
+	}
+	return strings.Trim(column, "`\"")
+}
+
+// 每一批的语句和参数，last 为 nil 时查询第一批
+func (a *SelectContext) chunkSQL(key string, last interface{}, size int) (string, []interface{}) {
+	params := make([]interface{}, 0, len(a.params)+3)
+	params = append(params, a.params...)
+	var conds []string
+	if a.where != "" {
+		conds = append(conds, "("+a.where+")")
+	}
+	key = quoteIdentifier(a.dialect, key)
+	if last != nil {
+		conds = append(conds, key+" > ?")
+		params = append(params, last)
+	}
+	sql := a.from
+	if len(conds) > 0 {
+		sql += " where " + strings.Join(conds, " and ")
+	}
+	sql += " order by " + key + " asc "
+	limit, lp := a.dialect.Limit(0, size)
+	return sql + limit, append(params, lp...)
+}
+
+// 从结果中读取 key 的值，key 可以带表别名，如 p.id
+func chunkKey(elem reflect.Type, key string) (func(v reflect.Value) (interface{}, error), error) {
+	tp := elem
+	if tp.Kind() == reflect.Ptr {
+		tp = tp.Elem()
+	}
+	if tp.Kind() != reflect.Struct || decoderOf(tp) != nil {
+		return func(v reflect.Value) (interface{}, error) {
+			return reflect.Indirect(v).Interface(), nil
+		}, nil
+	}
+	f, ok := metaOf(tp).columns[chunkColumn(key)]
+	if !ok {
+		return nil, fmt.Errorf("%w: chunk key %s not found in %s", ErrUnknownColumn, key, tp.String())
+	}
+	return func(v reflect.Value) (interface{}, error) {
+		fv, ok := fieldByIndex(reflect.Indirect(v), f.index)
+		if !ok {
+			return nil, nil
+		}
+		if f.encode != nil {
+			return f.encode(fv)
+		}
+		return fv.Interface(), nil
+	}, nil
+}
@@ -0,0 +1,131 @@
+package orm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChunk(t *testing.T) {
+	c := openTestContext(t)
+	insertNames(t, c, "a", "b", "c", "d", "e", "f", "g")
+	var sizes []int
+	var names []string
+	err := c.Select("person", []string{"id", "name"}, "age >= ?", 2).Chunk("id", 3, func(ps []*testPerson) error {
+		sizes = append(sizes, len(ps))
+		for _, p := range ps {
+			names = append(names, p.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sizes, []int{3, 3}) || strings.Join(names, "") != "bcdefg" {
+		t.Fatalf("sizes = %v, names = %v", sizes, names)
+	}
+	// key 带表别名，结果为 key 的值
+	var ids []int64
+	err = c.Select("person p", []string{"p.id"}, "").Chunk("p.id", 2, func(batch []int64) error {
+		ids = append(ids, batch...)
+		if len(ids) >= 4 {
+			return ErrStop
+		}
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(ids, []int64{1, 2, 3, 4}) {
+		t.Fatalf("ids = %v, %v", ids, err)
+	}
+	calls := 0
+	err = c.Select("person", []string{"id"}, "id > 100").Chunk("id", 2, func(batch []testPerson) error {
+		calls++
+		return nil
+	})
+	if err != nil || calls != 0 {
+		t.Fatalf("calls = %d, %v", calls, err)
+	}
+}
+
+func TestChunkErrors(t *testing.T) {
+	c := openTestContext(t)
+	insertNames(t, c, "a", "b", "c")
+	fn := func(batch []testPerson) error { return nil }
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{"key not selected", c.Select("person", []string{"name"}, "").Chunk("id", 2, fn)},
+		{"key not in struct", c.Select("person", []string{"name"}, "").Chunk("name2", 2, fn)},
+		{"order by", c.Select("person", nil, "").OrderByAsc("id").Chunk("id", 2, fn)},
+		{"search", c.Search("select * from person").Chunk("id", 2, fn)},
+		{"size", c.Select("person", nil, "").Chunk("id", 0, fn)},
+		{"fn", c.Select("person", nil, "").Chunk("id", 2, func(p testPerson) error { return nil })},
+	}
+	for _, cs := range cases {
+		if cs.err == nil {
+			t.Errorf("%s: want error", cs.name)
+		}
+	}
+}
+
+// key 的值无法写入字段时每一批都相同，不能重复查询
+func TestChunkKeyNotAdvancing(t *testing.T) {
+	c := openTestContext(t)
+	ps := []testPerson{{ID: 200}, {ID: 201}, {ID: 202}, {ID: 203}}
+	if _, err := c.Insert("person", []string{"id"}, ps).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	err := c.Select("person", []string{"id"}, "").Chunk("id", 2, func(batch []struct {
+		ID int8 `column:"id"` // 溢出，保持零值
+	}) error {
+		calls++
+		return nil
+	})
+	if err == nil || calls != 1 {
+		t.Fatalf("calls = %d, err = %v", calls, err)
+	}
+}
+
+func TestChunkSQL(t *testing.T) {
+	q := createSelectContext(nil, nil, nil, PostgreSQL, "person", []string{"id"}, "age > ?", 18)
+	sql, params := q.chunkSQL("id", nil, 10)
+	if want := `select id from person where (age > ?) order by "id" asc  limit ? offset ?`; sql != want {
+		t.Errorf("got  %s\nwant %s", sql, want)
+	}
+	if !reflect.DeepEqual(params, []interface{}{18, 10, 0}) {
+		t.Errorf("params = %v", params)
+	}
+	sql, params = q.chunkSQL("p.id", int64(5), 10)
+	if want := `select id from person where (age > ?) and "p"."id" > ? order by "p"."id" asc  limit ? offset ?`; sql != want {
+		t.Errorf("got  %s\nwant %s", sql, want)
+	}
+	if !reflect.DeepEqual(params, []interface{}{18, int64(5), 10, 0}) {
+		t.Errorf("params = %v", params)
+	}
+}
+
+func TestKeyAdvanced(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		last, next interface{}
+		want       bool
+	}{
+		{int64(3), int64(4), true},
+		{int64(3), int64(3), false},
+		{int64(3), int64(2), false},
+		{uint32(3), uint32(4), true},
+		{1.5, 1.5, false},
+		{"a", "b", true},
+		{"b", "b", false},
+		{now, now.Add(time.Second), true},
+		{now, now, false},
+		{int64(3), "4", true},
+	}
+	for _, c := range cases {
+		if got := keyAdvanced(c.last, c.next); got != c.want {
+			t.Errorf("keyAdvanced(%v, %v) = %v, want %v", c.last, c.next, got, c.want)
+		}
+	}
+}
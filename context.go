@@ -1,27 +1,31 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 )
 
 type Context struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect Dialect
+	source  *datasource
+	ctx     context.Context
 }
 
 func CreateContext() *Context {
-	if defaultDatasource != "" {
-		return &Context{db: ds[defaultDatasource]}
+	if source, ok := lookupDatasource(""); ok {
+		return &Context{db: source.db, dialect: source.dialect, source: source}
 	}
 	// no datasource registered
 	return nil
 }
 
 func CreateContextOf(datasource string) (*Context, error) {
-	db, ok := ds[datasource]
-	if !ok {
+	source, ok := lookupDatasource(datasource)
+	if !ok || datasource == "" {
 		return nil, InvalidDatasourceError{datasource: datasource}
 	}
-	return &Context{db: db}, nil
+	return &Context{db: source.db, dialect: source.dialect, source: source}, nil
 }
 
 // dataset 支持指针/结构体/结构体数组/结构体指针数组
@@ -33,30 +37,71 @@ func CreateContextOf(datasource string) (*Context, error) {
 // 6. *[]*struct
 // 不支持除结构体之外的类型 如 int, bool, float 等 也不支持多重指针如 **struct []**struct **[]struct 等
 func (a *Context) Insert(table string, columns []string, dataset interface{}) *InsertContext {
-	return createInsertContext(a.db, nil, table, columns, interfaceToArray(dataset)...)
+	return createInsertContext(a.ctx, a.db, nil, a.dialect, table, columns, dataset)
+}
+
+// 将查询结果插入 table，如 insert into archive (cols) select cols from person where ...
+// columns 为空时不指定插入的字段，query 的参数作为插入语句的参数，query 只能是 Select/Search 构建的语句
+func (a *Context) InsertFrom(table string, columns []string, query *SelectContext) *InsertContext {
+	return createInsertFromContext(a.ctx, a.db, nil, a.dialect, table, columns, query)
 }
 
 func (a *Context) Delete(table string, where string) *DeleteContext {
-	return createDeleteContext(a.db, nil, table, where)
+	return createDeleteContext(a.ctx, a.db, nil, a.dialect, table, where)
 }
 
 func (a *Context) Update(table string, setCols []string, where string) *UpdateContext {
-	return createUpdateContext(a.db, nil, table, setCols, where)
+	return createUpdateContext(a.ctx, a.db, nil, a.dialect, table, setCols, where)
+}
+
+// 按 keyCols 批量更新 dataset 中的每一行，dataset 支持的类型与 Insert 相同
+// 如 UpdateBatch("person", []string{"user_name"}, []string{"id"}, persons)
+func (a *Context) UpdateBatch(table string, setCols, keyCols []string, dataset interface{}) *BatchUpdateContext {
+	return createBatchUpdateContext(a.ctx, a.db, nil, a.dialect, table, setCols, keyCols, dataset)
 }
 
+// 配置了从库时在从库上执行，可通过 ForcePrimary 指定在主库上执行
 func (a *Context) Select(table string, columns []string, where string, params ...interface{}) *SelectContext {
-	return createSelectContext(a.db, nil, table, columns, where, params...)
+	c := createSelectContext(a.ctx, a.db, nil, a.dialect, table, columns, where, params...)
+	c.source = a.source
+	return c
 }
 
 // 直接传入语句和参数的查询
+// 配置了从库时在从库上执行，可通过 ForcePrimary 指定在主库上执行
 func (a *Context) Search(sql string, params ...interface{}) *SelectContext {
-	return &SelectContext{advanced: true, sql: sql, params: params, db: a.db}
+	return &SelectContext{advanced: true, sql: sql, params: params, db: a.db, source: a.source, dialect: a.dialect, ctx: a.ctx}
+}
+
+// 当前数据源的方言
+func (a *Context) Dialect() Dialect {
+	return a.dialect
+}
+
+// 之后创建的 Insert/Delete/Update/Select/Search 及事务均使用 ctx
+func (a *Context) WithContext(ctx context.Context) *Context {
+	c := *a
+	c.ctx = ctx
+	return &c
 }
 
 func (a *Context) Begin() (*TransactionContext, error) {
-	tx, err := a.db.Begin()
+	return a.BeginTx(a.ctx, nil)
+}
+
+// ctx 在事务提交或回滚前被取消时，事务将被回滚
+func (a *Context) BeginTx(ctx context.Context, opts *sql.TxOptions) (*TransactionContext, error) {
+	ctx = contextOf(ctx)
+	tx, err := a.db.BeginTx(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
-	return &TransactionContext{tx: tx}, nil
+	return &TransactionContext{tx: tx, dialect: a.dialect, ctx: ctx}, nil
+}
+
+func contextOf(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
 }
@@ -0,0 +1,83 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWithContext(t *testing.T) {
+	c := openTestContext(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cc := c.WithContext(ctx)
+	if _, err := cc.Insert("person", []string{"name"}, testPerson{Name: "a"}).Exec(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Insert err = %v, want context.Canceled", err)
+	}
+	var ps []testPerson
+	if err := cc.Select("person", []string{"id", "name"}, "").Result(&ps); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Select err = %v, want context.Canceled", err)
+	}
+	// 原有的 Context 不受影响
+	if _, err := c.Insert("person", []string{"name"}, testPerson{Name: "a"}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	// 单条语句的 ctx 优先
+	if _, err := cc.Insert("person", []string{"name"}, testPerson{Name: "b"}).WithContext(context.Background()).Exec(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBeginTx(t *testing.T) {
+	c := openTestContext(t)
+	tx, err := c.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Insert("person", []string{"name"}, testPerson{Name: "a"}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	tx, err = c.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Insert("person", []string{"name"}, testPerson{Name: "b"}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	if err := c.Select("person", []string{"name"}, "").Result(&names); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "b" {
+		t.Fatalf("names = %v, want [b]", names)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.BeginTx(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("BeginTx err = %v, want context.Canceled", err)
+	}
+}
+
+// ctx 取消后事务被回滚，无法提交
+func TestBeginTxCanceled(t *testing.T) {
+	c := openTestContext(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Insert("person", []string{"name"}, testPerson{Name: "a"}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := tx.Commit(); err == nil {
+		t.Fatal("want error when committing a canceled transaction")
+	}
+}
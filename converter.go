@@ -0,0 +1,139 @@
+package orm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// 无法修改的第三方类型的转换器
+// 实现了 sql.Scanner / driver.Valuer 的类型不需要注册
+type Converter struct {
+	// 写入数据库前将字段值转换为驱动支持的类型
+	ToDB func(v interface{}) (driver.Value, error)
+	// 将查询结果转换为字段的类型，src 不为 nil
+	FromDB func(src interface{}) (interface{}, error)
+}
+
+var (
+	convertersLock sync.RWMutex
+	converters     = make(map[reflect.Type]Converter)
+)
+
+// 为 sample 的类型注册转换器，如
+//
+//	orm.RegisterConverter(decimal.Decimal{}, orm.Converter{
+//		ToDB: func(v interface{}) (driver.Value, error) {
+//			return v.(decimal.Decimal).String(), nil
+//		},
+//		FromDB: func(src interface{}) (interface{}, error) {
+//			return decimal.NewFromString(string(src.([]byte)))
+//		},
+//	})
+func RegisterConverter(sample interface{}, c Converter) {
+	convertersLock.Lock()
+	converters[reflect.TypeOf(sample)] = c
+	convertersLock.Unlock()
+	resetMetaCache()
+}
+
+func converterOf(tp reflect.Type) (Converter, bool) {
+	convertersLock.RLock()
+	defer convertersLock.RUnlock()
+	c, ok := converters[tp]
+	return c, ok
+}
+
+var (
+	type_scanner = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	type_valuer  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+func decodeScanner(field reflect.Value, cv interface{}) error {
+	return field.Addr().Interface().(sql.Scanner).Scan(cv)
+}
+
+func converterDecoder(tp reflect.Type, fromDB func(src interface{}) (interface{}, error)) decoder {
+	return func(field reflect.Value, cv interface{}) error {
+		v, err := fromDB(cv)
+		if err != nil {
+			return err
+		}
+		rv := reflect.ValueOf(v)
+		if !rv.IsValid() || !rv.Type().AssignableTo(tp) {
+			return errors.New("converter of " + tp.String() + " returned a value of different type")
+		}
+		field.Set(rv)
+		return nil
+	}
+}
+
+// 将字段值转换为参数
+type encoder func(field reflect.Value) (interface{}, error)
+
+// 不需要转换的类型返回 nil，直接使用字段值作为参数
+func encoderOf(tp reflect.Type) encoder {
+	if c, ok := converterOf(tp); ok && c.ToDB != nil {
+		return func(field reflect.Value) (interface{}, error) {
+			return c.ToDB(field.Interface())
+		}
+	}
+	if tp.Implements(type_valuer) {
+		return nil
+	}
+	if reflect.PtrTo(tp).Implements(type_valuer) { // Value 定义在指针上
+		return func(field reflect.Value) (interface{}, error) {
+			if field.CanAddr() {
+				return field.Addr().Interface().(driver.Valuer).Value()
+			}
+			p := reflect.New(tp)
+			p.Elem().Set(field)
+			return p.Interface().(driver.Valuer).Value()
+		}
+	}
+	if tp.Kind() == reflect.Ptr {
+		e := encoderOf(tp.Elem())
+		if e == nil {
+			return nil
+		}
+		return func(field reflect.Value) (interface{}, error) {
+			if field.IsNil() {
+				return nil, nil
+			}
+			return e(field.Elem())
+		}
+	}
+	return nil
+}
+
+// tag 选项 json，如 `column:"settings,json"`
+// 写入时将字段序列化为 json 字符串，nil 的指针/map/slice 写入 NULL，查询时将结果反序列化到字段
+func encodeJSON(field reflect.Value) (interface{}, error) {
+	switch field.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		if field.IsNil() {
+			return nil, nil
+		}
+	}
+	b, err := json.Marshal(field.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func decodeJSON(field reflect.Value, cv interface{}) error {
+	var b []byte
+	switch v := cv.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return ErrUnsupportedConversion
+	}
+	return json.Unmarshal(b, field.Addr().Interface())
+}
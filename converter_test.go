@@ -0,0 +1,158 @@
+package orm
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 以小写保存，读取时转为大写
+type upperString string
+
+func (a *upperString) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		*a = upperString(strings.ToUpper(v))
+	case []byte:
+		*a = upperString(strings.ToUpper(string(v)))
+	default:
+		return errors.New("unsupported type")
+	}
+	return nil
+}
+
+func (a upperString) Value() (driver.Value, error) {
+	return strings.ToLower(string(a)), nil
+}
+
+// 模拟无法修改的第三方类型，以 "cents" 字符串保存
+type money struct {
+	cents int64
+}
+
+type converterPerson struct {
+	ID   int64       `column:"id,pk"`
+	Name upperString `column:"name"`
+	Age  money       `column:"age"`
+	Nick *money      `column:"nick"`
+}
+
+func (converterPerson) TableName() string { return "person" }
+
+func registerMoney() {
+	RegisterConverter(money{}, Converter{
+		ToDB: func(v interface{}) (driver.Value, error) {
+			return strconv.FormatInt(v.(money).cents, 10), nil
+		},
+		FromDB: func(src interface{}) (interface{}, error) {
+			var s string
+			switch v := src.(type) {
+			case string:
+				s = v
+			case []byte:
+				s = string(v)
+			case int64:
+				return money{cents: v}, nil
+			}
+			n, err := strconv.ParseInt(s, 10, 64)
+			return money{cents: n}, err
+		},
+	})
+}
+
+func TestScannerValuer(t *testing.T) {
+	c := openTestContext(t)
+	if _, err := c.Insert("person", []string{"name"}, testPerson{Name: "x"}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	type row struct {
+		Name upperString `column:"name"`
+	}
+	if _, err := c.Insert("person", []string{"name"}, row{Name: "AbC"}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	var raw []string
+	if err := c.Select("person", []string{"name"}, "").OrderByAsc("id").Result(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 2 || raw[1] != "abc" {
+		t.Fatalf("stored = %v, want Value() to be used", raw)
+	}
+	var rs []row
+	if err := c.Select("person", []string{"name"}, "").OrderByAsc("id").Result(&rs); err != nil {
+		t.Fatal(err)
+	}
+	if rs[0].Name != "X" || rs[1].Name != "ABC" {
+		t.Fatalf("rows = %+v, want Scan() to be used", rs)
+	}
+}
+
+func TestRegisterConverter(t *testing.T) {
+	registerMoney()
+	c := openTestContext(t)
+	nick := money{cents: 7}
+	ps := []converterPerson{{Name: "a", Age: money{cents: 150}, Nick: &nick}, {Name: "b"}}
+	if _, err := c.InsertModel(ps).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	var rs []converterPerson
+	if err := c.Select("person", []string{"id", "name", "age", "nick"}, "").OrderByAsc("id").Result(&rs); err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 || rs[0].Age.cents != 150 || rs[0].Nick == nil || rs[0].Nick.cents != 7 {
+		t.Fatalf("first row = %+v", rs[0])
+	}
+	if rs[1].Age.cents != 0 || rs[1].Nick != nil {
+		t.Fatalf("second row = %+v", rs[1])
+	}
+}
+
+type jsonPerson struct {
+	ID   int64          `column:"id,pk"`
+	Name map[string]int `column:"name,json"`
+	Nick *[]string      `column:"nick, json"`
+}
+
+func (jsonPerson) TableName() string { return "person" }
+
+func TestJSONTag(t *testing.T) {
+	c := openTestContext(t)
+	tags := []string{"a", "b"}
+	ps := []*jsonPerson{{Name: map[string]int{"x": 1}, Nick: &tags}, {}}
+	if _, err := c.InsertModel(ps).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	var raw []testPerson
+	if err := c.Select("person", []string{"name", "nick"}, "").OrderByAsc("id").Result(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw[0].Name != `{"x":1}` || raw[0].Nick == nil || *raw[0].Nick != `["a","b"]` {
+		t.Fatalf("stored = %+v", raw[0])
+	}
+	if raw[1].Nick != nil { // nil 写入 NULL
+		t.Fatalf("stored nil = %v", *raw[1].Nick)
+	}
+	var rs []jsonPerson
+	if err := c.Select("person", ColumnsExcept(jsonPerson{}), "").OrderByAsc("id").Result(&rs); err != nil {
+		t.Fatal(err)
+	}
+	if rs[0].Name["x"] != 1 || rs[0].Nick == nil || len(*rs[0].Nick) != 2 || (*rs[0].Nick)[1] != "b" {
+		t.Fatalf("first row = %+v", rs[0])
+	}
+	if rs[1].Name != nil || rs[1].Nick != nil {
+		t.Fatalf("second row = %+v", rs[1])
+	}
+}
+
+func TestJSONTagInvalid(t *testing.T) {
+	c := openTestContext(t)
+	if _, err := c.Insert("person", []string{"name"}, testPerson{Name: "{"}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	var rs []jsonPerson
+	if err := c.Select("person", []string{"name"}, "").Result(&rs); err == nil {
+		t.Fatal("want error for invalid json")
+	}
+}
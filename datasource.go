@@ -2,25 +2,59 @@ package orm
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	mysql "github.com/go-sql-driver/mysql"
 )
 
-var ds = make(map[string]*sql.DB)
+type datasource struct {
+	db       *sql.DB // 主库
+	dialect  Dialect
+	replicas []*replica    // 从库
+	slots    []int         // 按权重展开的从库下标
+	counter  atomic.Uint64 // 轮询计数，32 位平台上也保证 8 字节对齐
+	stop     chan struct{}
+	drain    time.Duration // 被替换后延迟关闭的时间
+}
+
+// ds, defaultDatasource 的读写均需持有 dsLock
+var dsLock sync.RWMutex
+
+var ds = make(map[string]*datasource)
 
 // default datasource   the first registered datasource
 var defaultDatasource string
 
 type DatasourceConfig struct {
-	name        string
-	dns         string
-	maxConn     int
-	maxIdleConn int
+	name            string
+	dns             string
+	driver          string
+	dialect         Dialect
+	maxConn         int
+	maxIdleConn     int
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+	replicas        []replicaConfig
+	healthCheck     time.Duration
+	drain           time.Duration
+	// 以下仅对 MySQL 生效，会覆盖 dns 中的同名参数
+	loc          *time.Location
+	timeout      time.Duration
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	tlsConfig    string
+	charset      string
+	collation    string
+	params       map[string]string
 }
 
 func NewDatasourceConfig(name, dns string) DatasourceConfig {
-	return DatasourceConfig{name: name, dns: dns}
+	return DatasourceConfig{name: name, dns: dns, dialect: MySQL, drain: defaultDrainTimeout}
 }
 
 func (a DatasourceConfig) MaxConn(m int) DatasourceConfig {
@@ -33,29 +67,311 @@ func (a DatasourceConfig) MaxIdleConn(m int) DatasourceConfig {
 	return a
 }
 
+// 连接的最大存活时间
+func (a DatasourceConfig) ConnMaxLifetime(d time.Duration) DatasourceConfig {
+	a.connMaxLifetime = d
+	return a
+}
+
+// 连接的最大空闲时间
+func (a DatasourceConfig) ConnMaxIdleTime(d time.Duration) DatasourceConfig {
+	a.connMaxIdleTime = d
+	return a
+}
+
+// 时间字段的时区，默认为 time.Local
+func (a DatasourceConfig) Location(loc *time.Location) DatasourceConfig {
+	a.loc = loc
+	return a
+}
+
+// 建立连接的超时时间
+func (a DatasourceConfig) Timeout(d time.Duration) DatasourceConfig {
+	a.timeout = d
+	return a
+}
+
+// I/O 读超时时间
+func (a DatasourceConfig) ReadTimeout(d time.Duration) DatasourceConfig {
+	a.readTimeout = d
+	return a
+}
+
+// I/O 写超时时间
+func (a DatasourceConfig) WriteTimeout(d time.Duration) DatasourceConfig {
+	a.writeTimeout = d
+	return a
+}
+
+// TLS 配置名称，如 "true", "skip-verify" 或通过 mysql.RegisterTLSConfig 注册的名称
+func (a DatasourceConfig) TLSConfig(name string) DatasourceConfig {
+	a.tlsConfig = name
+	return a
+}
+
+func (a DatasourceConfig) Charset(charset string) DatasourceConfig {
+	a.charset = charset
+	return a
+}
+
+func (a DatasourceConfig) Collation(collation string) DatasourceConfig {
+	a.collation = collation
+	return a
+}
+
+// 其他驱动参数，如 Param("sql_mode", "'TRADITIONAL'")
+func (a DatasourceConfig) Param(key, value string) DatasourceConfig {
+	params := make(map[string]string, len(a.params)+1)
+	for k, v := range a.params {
+		params[k] = v
+	}
+	params[key] = value
+	a.params = params
+	return a
+}
+
+// 添加从库，从库与主库使用相同的配置
+// Context 的 Select/Search 在从库上执行，Insert/Update/Delete/Begin 在主库上执行
+func (a DatasourceConfig) Replica(dns string) DatasourceConfig {
+	return a.WeightedReplica(dns, 1)
+}
+
+// 添加带权重的从库，查询按权重轮询分配到健康的从库上
+func (a DatasourceConfig) WeightedReplica(dns string, weight int) DatasourceConfig {
+	if weight <= 0 {
+		weight = 1
+	}
+	replicas := make([]replicaConfig, 0, len(a.replicas)+1)
+	replicas = append(replicas, a.replicas...)
+	a.replicas = append(replicas, replicaConfig{dns: dns, weight: weight})
+	return a
+}
+
+// 从库健康检查的间隔，默认 10 秒，ping 的超时时间为 Timeout，未设置时为检查间隔
+func (a DatasourceConfig) HealthCheckInterval(d time.Duration) DatasourceConfig {
+	a.healthCheck = d
+	return a
+}
+
+// 同名数据源被替换后，原有连接延迟关闭的时间，默认 1 分钟
+// 期间此前创建的 Context/TransactionContext 仍然可以使用原有连接，为 0 时立即关闭
+func (a DatasourceConfig) DrainTimeout(d time.Duration) DatasourceConfig {
+	a.drain = d
+	return a
+}
+
+// 数据库方言，默认为 MySQL
+func (a DatasourceConfig) Dialect(d Dialect) DatasourceConfig {
+	a.dialect = d
+	return a
+}
+
+// 驱动名称，默认使用方言的 DriverName，如 PostgreSQL 使用 pgx 驱动时需指定为 "pgx"
+func (a DatasourceConfig) Driver(driver string) DatasourceConfig {
+	a.driver = driver
+	return a
+}
+
+// 注册数据源，失败时 panic
+// Deprecated: 使用 RegisterDatasource
 func RegisterDatsource(config DatasourceConfig) {
-	cfg, err := mysql.ParseDSN(config.dns)
-	if err != nil {
-		panic("mysql dns error:" + err.Error())
+	if err := RegisterDatasource(config); err != nil {
+		panic(err.Error())
 	}
-	cfg.ParseTime = true
-	cfg.Loc = time.Local
-	db, err := sql.Open("mysql", cfg.FormatDSN())
+}
+
+// 注册数据源，同名数据源已存在时替换，可用于更换账号密码
+// 原有连接在 DrainTimeout 后关闭，之后通过 CreateContext 获取的 Context 使用新的连接
+// 从库连接失败时不会返回异常，从库初始为不健康，由后台健康检查连接成功后开始使用，此前查询在主库上执行
+func RegisterDatasource(config DatasourceConfig) error {
+	dialect := config.dialect
+	if dialect == nil {
+		dialect = MySQL
+	}
+	db, err := config.open(dialect, config.dns)
 	if err != nil {
-		panic("connect to database error:" + err.Error())
+		return err
 	}
 	err = db.Ping()
 	if err != nil {
-		panic("ping database error:" + err.Error())
+		db.Close()
+		return fmt.Errorf("ping database error: %w", err)
+	}
+	source := &datasource{db: db, dialect: dialect, drain: config.drain}
+	for _, rc := range config.replicas {
+		rdb, err := config.open(dialect, rc.dns)
+		if err != nil {
+			source.close()
+			return fmt.Errorf("replica: %w", err)
+		}
+		source.addReplica(rdb, rc.weight)
+	}
+	source.startHealthCheck(config.healthCheck, config.timeout)
+
+	return register(config.name, source)
+}
+
+func (a DatasourceConfig) open(dialect Dialect, dns string) (*sql.DB, error) {
+	dsn := dns
+	if dialect.Name() == MySQL.Name() {
+		var err error
+		dsn, err = a.mysqlDSN(dns)
+		if err != nil {
+			return nil, err
+		}
+	}
+	driver := a.driver
+	if driver == "" {
+		driver = dialect.DriverName()
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("connect to database error: %w", err)
 	}
-	maxConn := config.maxConn
-	maxIdleConn := config.maxIdleConn
+	maxConn := a.maxConn
+	maxIdleConn := a.maxIdleConn
 	db.SetMaxOpenConns(maxConn)
 	db.SetMaxIdleConns(maxIdleConn)
+	db.SetConnMaxLifetime(a.connMaxLifetime)
+	db.SetConnMaxIdleTime(a.connMaxIdleTime)
+	return db, nil
+}
 
-	ds[config.name] = db
+// 将配置应用到 dns 上，ParseTime 总是为 true
+func (a DatasourceConfig) mysqlDSN(dns string) (string, error) {
+	cfg, err := mysql.ParseDSN(dns)
+	if err != nil {
+		return "", fmt.Errorf("mysql dns error: %w", err)
+	}
+	cfg.ParseTime = true
+	cfg.Loc = time.Local
+	if a.loc != nil {
+		cfg.Loc = a.loc
+	}
+	if a.timeout > 0 {
+		cfg.Timeout = a.timeout
+	}
+	if a.readTimeout > 0 {
+		cfg.ReadTimeout = a.readTimeout
+	}
+	if a.writeTimeout > 0 {
+		cfg.WriteTimeout = a.writeTimeout
+	}
+	if a.tlsConfig != "" {
+		cfg.TLSConfig = a.tlsConfig
+	}
+	if a.collation != "" {
+		cfg.Collation = a.collation
+	}
+	if a.charset != "" || len(a.params) > 0 {
+		if cfg.Params == nil {
+			cfg.Params = make(map[string]string, len(a.params)+1)
+		}
+		for k, v := range a.params {
+			cfg.Params[k] = v
+		}
+		if a.charset != "" {
+			cfg.Params["charset"] = a.charset
+		}
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// 注册已经打开的数据库连接，如测试时使用的 sqlmock 或本地 SQLite 文件
+// 同名数据源已存在时替换，原有连接在 1 分钟后关闭
+func RegisterDB(name string, db *sql.DB, dialect Dialect) error {
+	if db == nil {
+		return errors.New("nil db of datasource " + name)
+	}
+	if dialect == nil {
+		dialect = MySQL
+	}
+	return register(name, &datasource{db: db, dialect: dialect, drain: defaultDrainTimeout})
+}
+
+func register(name string, source *datasource) error {
+	dsLock.Lock()
+	old, ok := ds[name]
+	ds[name] = source
+	if defaultDatasource == "" {
+		defaultDatasource = name
+	}
+	dsLock.Unlock()
+	if !ok {
+		return nil
+	}
+	return old.retire(source.db, source.drain)
+}
+
+// 移除并关闭数据源，移除的是默认数据源时，下一个注册的数据源将成为默认数据源，也可通过 SetDefaultDatasource 重新设置
+func UnregisterDatasource(name string) error {
+	dsLock.Lock()
+	source, ok := ds[name]
+	if !ok {
+		dsLock.Unlock()
+		return InvalidDatasourceError{datasource: name}
+	}
+	delete(ds, name)
+	if defaultDatasource == name {
+		defaultDatasource = ""
+	}
+	dsLock.Unlock()
+	return source.close()
+}
+
+// 关闭并移除所有数据源，用于程序退出，返回关闭过程中的第一个异常
+func CloseAll() error {
+	dsLock.Lock()
+	sources := ds
+	ds = make(map[string]*datasource)
+	defaultDatasource = ""
+	dsLock.Unlock()
+	var err error
+	for _, source := range sources {
+		if e := source.close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// 设置默认数据源，数据源必须已经注册
+func SetDefaultDatasource(name string) error {
+	dsLock.Lock()
+	defer dsLock.Unlock()
+	if _, ok := ds[name]; !ok {
+		return InvalidDatasourceError{datasource: name}
+	}
+	defaultDatasource = name
+	return nil
+}
+
+// 默认数据源名称，未注册任何数据源时返回空字符串
+func DefaultDatasource() string {
+	dsLock.RLock()
+	defer dsLock.RUnlock()
+	return defaultDatasource
+}
+
+// 所有已注册的数据源名称，按名称排序
+func Datasources() []string {
+	dsLock.RLock()
+	names := make([]string, 0, len(ds))
+	for name := range ds {
+		names = append(names, name)
+	}
+	dsLock.RUnlock()
+	sort.Strings(names)
+	return names
+}
 
-	if len(ds) == 1 {
-		defaultDatasource = config.name
+// name 为空时返回默认数据源
+func lookupDatasource(name string) (*datasource, bool) {
+	dsLock.RLock()
+	defer dsLock.RUnlock()
+	if name == "" {
+		name = defaultDatasource
 	}
+	source, ok := ds[name]
+	return source, ok
 }
@@ -0,0 +1,303 @@
+package orm
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	mysql "github.com/go-sql-driver/mysql"
+)
+
+func openTestDB(t testing.TB) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// 等待 db 被关闭
+func waitClosed(t *testing.T, db *sql.DB) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for db.Ping() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("db is not closed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRegisterDatasource(t *testing.T) {
+	name := t.Name()
+	if err := RegisterDatasource(NewDatasourceConfig(name, ":memory:").Dialect(SQLite).MaxConn(1)); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterDatasource(name)
+	c, err := CreateContextOf(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Dialect() != SQLite {
+		t.Fatalf("dialect = %s", c.Dialect().Name())
+	}
+	var one int
+	if err := c.Search("select 1").Result(&one); err != nil || one != 1 {
+		t.Fatalf("select 1 = %d, %v", one, err)
+	}
+}
+
+func TestRegisterDatasourceError(t *testing.T) {
+	name := t.Name()
+	if err := RegisterDatasource(NewDatasourceConfig(name, ":memory:").Dialect(SQLite).Driver("unknown")); err == nil {
+		t.Fatal("want error for unknown driver")
+	}
+	if err := RegisterDatasource(NewDatasourceConfig(name, "tcp(")); err == nil {
+		t.Fatal("want error for invalid mysql dns")
+	}
+	if _, err := CreateContextOf(name); err == nil {
+		t.Fatal("datasource registered after error")
+	}
+	if err := RegisterDB(name, nil, SQLite); err == nil {
+		t.Fatal("want error for nil db")
+	}
+}
+
+func TestUnregisterDatasource(t *testing.T) {
+	name := t.Name()
+	db := openTestDB(t)
+	if err := RegisterDB(name, db, SQLite); err != nil {
+		t.Fatal(err)
+	}
+	if err := UnregisterDatasource(name); err != nil {
+		t.Fatal(err)
+	}
+	if db.Ping() == nil {
+		t.Fatal("db is not closed")
+	}
+	var ie InvalidDatasourceError
+	if err := UnregisterDatasource(name); !errors.As(err, &ie) {
+		t.Fatalf("err = %v, want InvalidDatasourceError", err)
+	}
+}
+
+// 替换后原有连接在 drain 期间仍然可用，之后关闭
+func TestReregisterDrain(t *testing.T) {
+	name := t.Name()
+	old := openTestDB(t)
+	if err := RegisterDB(name, old, SQLite); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterDatasource(name)
+	c, _ := CreateContextOf(name)
+	if err := RegisterDatasource(NewDatasourceConfig(name, ":memory:").Dialect(SQLite).DrainTimeout(50 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	var one int
+	if err := c.Search("select 1").Result(&one); err != nil {
+		t.Fatalf("old context during drain: %v", err)
+	}
+	nc, _ := CreateContextOf(name)
+	if nc.db == old {
+		t.Fatal("new context uses the replaced db")
+	}
+	waitClosed(t, old)
+	if err := nc.Search("select 1").Result(&one); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReregisterWithoutDrain(t *testing.T) {
+	name := t.Name()
+	old := openTestDB(t)
+	if err := RegisterDB(name, old, SQLite); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterDatasource(name)
+	if err := RegisterDatasource(NewDatasourceConfig(name, ":memory:").Dialect(SQLite).DrainTimeout(0)); err != nil {
+		t.Fatal(err)
+	}
+	if old.Ping() == nil {
+		t.Fatal("db is not closed")
+	}
+}
+
+// 使用同一个 db 重新注册时不关闭
+func TestReregisterSameDB(t *testing.T) {
+	name := t.Name()
+	db := openTestDB(t)
+	if err := RegisterDB(name, db, SQLite); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterDatasource(name)
+	if err := register(name, &datasource{db: db, dialect: SQLite}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCloseAll(t *testing.T) {
+	a, b := openTestDB(t), openTestDB(t)
+	if err := RegisterDB(t.Name()+"a", a, SQLite); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterDB(t.Name()+"b", b, SQLite); err != nil {
+		t.Fatal(err)
+	}
+	if err := CloseAll(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Ping() == nil || b.Ping() == nil {
+		t.Fatal("db is not closed")
+	}
+	if _, err := CreateContextOf(t.Name() + "a"); err == nil {
+		t.Fatal("datasource is not removed")
+	}
+}
+
+func TestDefaultDatasource(t *testing.T) {
+	CloseAll()
+	if DefaultDatasource() != "" || CreateContext() != nil {
+		t.Fatal("default datasource without registration")
+	}
+	a, b := t.Name()+"a", t.Name()+"b"
+	if err := RegisterDB(b, openTestDB(t), SQLite); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterDB(a, openTestDB(t), SQLite); err != nil {
+		t.Fatal(err)
+	}
+	defer CloseAll()
+	if got := DefaultDatasource(); got != b {
+		t.Fatalf("default = %s, want the first registered %s", got, b)
+	}
+	if got := Datasources(); len(got) != 2 || got[0] != a || got[1] != b {
+		t.Fatalf("Datasources() = %v", got)
+	}
+	var ie InvalidDatasourceError
+	if err := SetDefaultDatasource("missing"); !errors.As(err, &ie) {
+		t.Fatalf("err = %v, want InvalidDatasourceError", err)
+	}
+	if err := SetDefaultDatasource(a); err != nil {
+		t.Fatal(err)
+	}
+	if c := CreateContext(); c == nil || c.source != ds[a] {
+		t.Fatal("CreateContext does not use the new default")
+	}
+	// 移除默认数据源后，下一个注册的数据源成为默认数据源
+	if err := UnregisterDatasource(a); err != nil {
+		t.Fatal(err)
+	}
+	if DefaultDatasource() != "" {
+		t.Fatal("default is not cleared")
+	}
+	if err := RegisterDB(a, openTestDB(t), SQLite); err != nil {
+		t.Fatal(err)
+	}
+	if got := DefaultDatasource(); got != a {
+		t.Fatalf("default = %s, want %s", got, a)
+	}
+}
+
+// 需要通过 go test -race 运行
+func TestDatasourceConcurrency(t *testing.T) {
+	name := t.Name()
+	if err := RegisterDB(name, openTestDB(t), SQLite); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterDatasource(name)
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for j := 0; j < 100; j++ {
+				if _, err := CreateContextOf(name); err != nil {
+					t.Error(err)
+					return
+				}
+				Datasources()
+				DefaultDatasource()
+			}
+		}()
+	}
+	for j := 0; j < 20; j++ {
+		if err := register(name, &datasource{db: openTestDB(t), dialect: SQLite}); err != nil {
+			t.Fatal(err)
+		}
+		SetDefaultDatasource(name)
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+}
+
+func TestMysqlDSN(t *testing.T) {
+	config := NewDatasourceConfig("", "user:pwd@tcp(127.0.0.1:3306)/test?charset=latin1&timeout=1s").
+		Location(time.UTC).
+		Timeout(3*time.Second).
+		ReadTimeout(4*time.Second).
+		WriteTimeout(5*time.Second).
+		TLSConfig("skip-verify").
+		Charset("utf8mb4").
+		Collation("utf8mb4_general_ci").
+		Param("sql_mode", "'TRADITIONAL'")
+	dsn, err := config.mysqlDSN(config.dns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.User != "user" || cfg.Passwd != "pwd" || cfg.Addr != "127.0.0.1:3306" || cfg.DBName != "test" {
+		t.Errorf("connection = %+v", cfg)
+	}
+	if !cfg.ParseTime || cfg.Loc != time.UTC {
+		t.Errorf("ParseTime = %v, Loc = %v", cfg.ParseTime, cfg.Loc)
+	}
+	if cfg.Timeout != 3*time.Second || cfg.ReadTimeout != 4*time.Second || cfg.WriteTimeout != 5*time.Second {
+		t.Errorf("timeouts = %v %v %v", cfg.Timeout, cfg.ReadTimeout, cfg.WriteTimeout)
+	}
+	if cfg.TLSConfig != "skip-verify" || cfg.Collation != "utf8mb4_general_ci" {
+		t.Errorf("TLSConfig = %s, Collation = %s", cfg.TLSConfig, cfg.Collation)
+	}
+	if cfg.Params["charset"] != "utf8mb4" || cfg.Params["sql_mode"] != "'TRADITIONAL'" {
+		t.Errorf("params = %v", cfg.Params)
+	}
+
+	// 未设置的选项保留 dns 中的值
+	config = NewDatasourceConfig("", "user:pwd@tcp(127.0.0.1:3306)/test?charset=latin1&readTimeout=2s")
+	dsn, _ = config.mysqlDSN(config.dns)
+	cfg, _ = mysql.ParseDSN(dsn)
+	if cfg.Params["charset"] != "latin1" || cfg.ReadTimeout != 2*time.Second || cfg.Loc != time.Local {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if _, err := config.mysqlDSN("tcp("); err == nil {
+		t.Error("want error for invalid dns")
+	}
+}
+
+// Param 返回新的配置，不影响原有配置
+func TestDatasourceConfigParam(t *testing.T) {
+	base := NewDatasourceConfig("", "").Param("a", "1")
+	c := base.Param("b", "2")
+	if len(base.params) != 1 || len(c.params) != 2 {
+		t.Fatalf("base = %v, c = %v", base.params, c.params)
+	}
+}
+
+func TestDatasourcePool(t *testing.T) {
+	config := NewDatasourceConfig("", ":memory:").Dialect(SQLite).MaxConn(3).MaxIdleConn(2)
+	db, err := config.open(SQLite, config.dns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := db.Stats().MaxOpenConnections; got != 3 {
+		t.Fatalf("MaxOpenConnections = %d, want 3", got)
+	}
+}
@@ -1,8 +1,10 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"strings"
 )
 
@@ -14,14 +16,21 @@ type DeleteContext struct {
 	params    []interface{}
 	db        *sql.DB
 	tx        *sql.Tx
+	dialect   Dialect
+	ctx       context.Context
 }
 
-func createDeleteContext(db *sql.DB, tx *sql.Tx, table string, where string) *DeleteContext {
+func createDeleteContext(ctx context.Context, db *sql.DB, tx *sql.Tx, dialect Dialect, table string, where string) *DeleteContext {
 	if strings.TrimSpace(where) == "" {
-		return &DeleteContext{build: false, err: errors.New(`for security. can't delete without [where] parameter. to delete all dataset, pass "1=1" to [where] parameter`)}
+		return &DeleteContext{build: false, err: fmt.Errorf(`%w. for security. can't delete without [where] parameter. to delete all dataset, pass "1=1" to [where] parameter`, ErrMissingWhere)}
 	}
 	sql := "delete from " + table + " where " + where
-	return &DeleteContext{build: false, db: db, tx: tx, sql: sql}
+	return &DeleteContext{build: false, db: db, tx: tx, dialect: dialect, ctx: ctx, sql: sql}
+}
+
+func (a *DeleteContext) WithContext(ctx context.Context) *DeleteContext {
+	a.ctx = ctx
+	return a
 }
 
 // 直接传递所有参数
@@ -57,7 +66,7 @@ func (a *DeleteContext) Exec() (int64, error) {
 	if a.sql == "" {
 		return 0, nil
 	}
-	return execute(a.db, a.tx, a.sql, a.params...)
+	return execute(a.ctx, a.db, a.tx, a.dialect, a.sql, a.params...)
 }
 
 // 返回 DeleteContext 构建过程中的异常
@@ -67,5 +76,5 @@ func (a *DeleteContext) ContextError() error {
 
 // 返回语句和参数
 func (a *DeleteContext) Desc() (string, []interface{}) {
-	return a.sql, a.params
+	return rebind(a.dialect, a.sql), a.params
 }
@@ -0,0 +1,182 @@
+package orm
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 数据库方言
+// 语句构建时统一使用 ? 作为占位符，执行前再由方言转换为对应的形式
+type Dialect interface {
+	// 方言名称
+	Name() string
+	// database/sql 注册的驱动名称
+	DriverName() string
+	// 第 index 个参数的占位符，index 从 1 开始
+	Placeholder(index int) string
+	// 为表名/字段名加上引号
+	Quote(identifier string) string
+	// 分页语句及其参数
+	Limit(offset, size int) (string, []interface{})
+	// 是否通过 returning 子句获取自增主键，否则使用 LastInsertId
+	Returning() bool
+	// 单条语句允许的最大参数个数，批量插入时按此拆分，0 表示不限制
+	MaxParams() int
+}
+
+var (
+	MySQL      Dialect = mysqlDialect{}
+	PostgreSQL Dialect = postgresDialect{}
+	SQLite     Dialect = sqliteDialect{}
+)
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) DriverName() string {
+	return "mysql"
+}
+
+func (mysqlDialect) Placeholder(index int) string {
+	return "?"
+}
+
+func (mysqlDialect) Quote(identifier string) string {
+	return quote(identifier, "`")
+}
+
+func (mysqlDialect) Limit(offset, size int) (string, []interface{}) {
+	return " limit ?, ?", []interface{}{offset, size}
+}
+
+func (mysqlDialect) Returning() bool {
+	return false
+}
+
+func (mysqlDialect) MaxParams() int {
+	return 65535
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+func (postgresDialect) DriverName() string {
+	return "postgres"
+}
+
+func (postgresDialect) Placeholder(index int) string {
+	return "$" + strconv.Itoa(index)
+}
+
+func (postgresDialect) Quote(identifier string) string {
+	return quote(identifier, `"`)
+}
+
+func (postgresDialect) Limit(offset, size int) (string, []interface{}) {
+	return " limit ? offset ?", []interface{}{size, offset}
+}
+
+func (postgresDialect) Returning() bool {
+	return true
+}
+
+func (postgresDialect) MaxParams() int {
+	return 65535
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return "sqlite3"
+}
+
+func (sqliteDialect) DriverName() string {
+	return "sqlite3"
+}
+
+func (sqliteDialect) Placeholder(index int) string {
+	return "?"
+}
+
+func (sqliteDialect) Quote(identifier string) string {
+	return quote(identifier, `"`)
+}
+
+func (sqliteDialect) Limit(offset, size int) (string, []interface{}) {
+	return " limit ? offset ?", []interface{}{size, offset}
+}
+
+func (sqliteDialect) Returning() bool {
+	return false
+}
+
+// SQLITE_MAX_VARIABLE_NUMBER，3.32.0 之前的版本为 999，需要通过 InsertContext.BatchParams 指定
+func (sqliteDialect) MaxParams() int {
+	return 32766
+}
+
+// 按 . 分段加引号，如 db.table -> `db`.`table`
+func quote(identifier, q string) string {
+	parts := strings.Split(identifier, ".")
+	for i, p := range parts {
+		parts[i] = q + strings.ReplaceAll(p, q, q+q) + q
+	}
+	return strings.Join(parts, ".")
+}
+
+// 为 ORM 生成语句中的表名/字段名加上引号，避免与关键字冲突(如 order, user)
+// 已经加了引号或不是标识符(如 count(1), person p)时保持不变
+func quoteIdentifier(d Dialect, identifier string) string {
+	if d == nil || identifier == "" {
+		return identifier
+	}
+	for _, c := range identifier {
+		if c != '_' && c != '.' && c != '$' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			return identifier
+		}
+	}
+	return d.Quote(identifier)
+}
+
+func quoteIdentifiers(d Dialect, identifiers []string) []string {
+	rs := make([]string, 0, len(identifiers))
+	for _, e := range identifiers {
+		rs = append(rs, quoteIdentifier(d, e))
+	}
+	return rs
+}
+
+// 将语句中的 ? 替换为方言对应的占位符，引号内的 ? 不做替换
+func rebind(d Dialect, sql string) string {
+	if d == nil || d.Placeholder(1) == "?" {
+		return sql
+	}
+	var sb strings.Builder
+	sb.Grow(len(sql) + 8)
+	index := 0
+	var quoted byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quoted != 0:
+			if c == quoted {
+				quoted = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quoted = c
+		case c == '?':
+			index++
+			sb.WriteString(d.Placeholder(index))
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,77 @@
+package orm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRebind(t *testing.T) {
+	cases := []struct {
+		d    Dialect
+		sql  string
+		want string
+	}{
+		{MySQL, "select * from t where a = ? and b = ?", "select * from t where a = ? and b = ?"},
+		{SQLite, "select * from t where a = ?", "select * from t where a = ?"},
+		{PostgreSQL, "select * from t where a = ? and b = ?", "select * from t where a = $1 and b = $2"},
+		{PostgreSQL, "select '?', \"?\" from t where a = ?", "select '?', \"?\" from t where a = $1"},
+		{PostgreSQL, "update t set a = 'it''s?' where b = ?", "update t set a = 'it''s?' where b = $1"},
+		{nil, "a = ?", "a = ?"},
+	}
+	for _, c := range cases {
+		if got := rebind(c.d, c.sql); got != c.want {
+			t.Errorf("rebind(%v, %q) = %q, want %q", c.d, c.sql, got, c.want)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	cases := []struct {
+		d    Dialect
+		in   string
+		want string
+	}{
+		{MySQL, "order", "`order`"},
+		{MySQL, "db.user", "`db`.`user`"},
+		{PostgreSQL, "user", `"user"`},
+		{SQLite, `a"b`, `"a""b"`},
+	}
+	for _, c := range cases {
+		if got := c.d.Quote(c.in); got != c.want {
+			t.Errorf("%s.Quote(%q) = %q, want %q", c.d.Name(), c.in, got, c.want)
+		}
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"order", `"order"`},
+		{"p.id", `"p"."id"`},
+		{`"user"`, `"user"`},     // 已经加了引号
+		{"count(1)", "count(1)"}, // 表达式
+		{"person p", "person p"}, // 带别名
+		{"*", "*"},
+	}
+	for _, c := range cases {
+		if got := quoteIdentifier(PostgreSQL, c.in); got != c.want {
+			t.Errorf("quoteIdentifier(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+	if got := quoteIdentifier(nil, "order"); got != "order" {
+		t.Errorf("quoteIdentifier(nil) = %q", got)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	sql, params := MySQL.Limit(20, 10)
+	if sql != " limit ?, ?" || !reflect.DeepEqual(params, []interface{}{20, 10}) {
+		t.Errorf("MySQL.Limit = %q %v", sql, params)
+	}
+	sql, params = PostgreSQL.Limit(20, 10)
+	if sql != " limit ? offset ?" || !reflect.DeepEqual(params, []interface{}{10, 20}) {
+		t.Errorf("PostgreSQL.Limit = %q %v", sql, params)
+	}
+}
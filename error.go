@@ -1,9 +1,117 @@
 package orm
 
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+
+	mysql "github.com/go-sql-driver/mysql"
+)
+
+var (
+	// 没有查询到数据
+	ErrNoRows = sql.ErrNoRows
+	// 期望一行数据，查询到了多行
+	ErrMultipleRows = errors.New("multiple rows in result set")
+	// 违反唯一约束
+	ErrDuplicateKey = errors.New("duplicate key")
+	// 违反外键约束
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	// 死锁
+	ErrDeadlock = errors.New("deadlock")
+	// 等待锁超时
+	ErrLockWaitTimeout = errors.New("lock wait timeout")
+	// update/delete 没有 where 条件
+	ErrMissingWhere = errors.New("missing [where] parameter")
+	// update 没有需要更新的字段
+	ErrNoSetColumns = errors.New("no [set] columns to update")
+	// 字段不存在，包括结构体中不存在对应 tag 的字段以及数据库返回的字段不存在
+	ErrUnknownColumn = errors.New("unknown column")
+)
+
+// 数据库返回的异常
+// 可以通过 errors.Is(err, ErrDuplicateKey) 判断异常类型，通过 errors.As(err, &mysqlErr) 获取驱动的原始异常
+type DBError struct {
+	Kind error // ErrDuplicateKey, ErrDeadlock 等
+	Err  error // 驱动的原始异常，如 *mysql.MySQLError
+}
+
+func (a *DBError) Error() string {
+	return a.Kind.Error() + ": " + a.Err.Error()
+}
+
+func (a *DBError) Is(target error) bool {
+	return target == a.Kind
+}
+
+func (a *DBError) Unwrap() error {
+	return a.Err
+}
+
+// 对驱动返回的异常分类，无法分类的异常原样返回
+func wrapDBError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if kind := classify(err); kind != nil {
+		return &DBError{Kind: kind, Err: err}
+	}
+	return err
+}
+
+func classify(err error) error {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		switch me.Number {
+		case 1062, 1586:
+			return ErrDuplicateKey
+		case 1216, 1217, 1451, 1452:
+			return ErrForeignKeyViolation
+		case 1213:
+			return ErrDeadlock
+		case 1205:
+			return ErrLockWaitTimeout
+		case 1054:
+			return ErrUnknownColumn
+		}
+		return nil
+	}
+	var se interface{ SQLState() string } // PostgreSQL 驱动(pq, pgx)的异常
+	if errors.As(err, &se) {
+		switch se.SQLState() {
+		case "23505":
+			return ErrDuplicateKey
+		case "23503":
+			return ErrForeignKeyViolation
+		case "40P01":
+			return ErrDeadlock
+		case "55P03":
+			return ErrLockWaitTimeout
+		case "42703":
+			return ErrUnknownColumn
+		}
+		return nil
+	}
+	// SQLite 驱动的异常只能通过信息区分
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "UNIQUE constraint failed"):
+		return ErrDuplicateKey
+	case strings.Contains(msg, "FOREIGN KEY constraint failed"):
+		return ErrForeignKeyViolation
+	case strings.Contains(msg, "database is locked"):
+		return ErrLockWaitTimeout
+	case strings.Contains(msg, "no such column"):
+		return ErrUnknownColumn
+	}
+	return nil
+}
+
 type InvalidResultTypeError struct{}
 
 func (a InvalidResultTypeError) Error() string {
-	return "invalid type of result, result must be a ptr which pointers to slice(slice of struct/pointers), or struct, or pointer to struct"
+	return "invalid type of result, result must be a ptr which pointers to slice(slice of struct/pointers/map/basic type), or struct, or pointer to struct, or map[string]interface{}, or basic type"
 }
 
 type InvalidDatasourceError struct {
@@ -13,3 +121,36 @@ type InvalidDatasourceError struct {
 func (a InvalidDatasourceError) Error() string {
 	return "invalid datasource" + a.datasource
 }
+
+var (
+	// 查询结果中的 column 没有对应的字段，仅在严格映射模式下返回
+	ErrUnmappedColumn = errors.New("column is not mapped to any field")
+	// 查询结果的类型无法转换为字段的类型
+	ErrUnsupportedConversion = errors.New("unsupported conversion")
+	// 查询结果超出了字段类型的范围，如 int64 写入 int8
+	ErrOverflow = errors.New("value out of range")
+)
+
+// 查询结果写入字段时的异常，可通过 errors.Is 判断 Err 的类型
+type MappingError struct {
+	Column     string
+	Field      string       // 字段名，column 没有对应的字段时为空
+	FieldType  reflect.Type // 字段类型，column 没有对应的字段时为 nil
+	DriverType reflect.Type // 驱动返回的数据类型，构建映射时发现的异常为 nil
+	Err        error
+}
+
+func (a *MappingError) Error() string {
+	msg := "column '" + a.Column + "'"
+	if a.Field != "" {
+		msg += " -> field " + a.Field + " (" + a.FieldType.String() + ")"
+	}
+	if a.DriverType != nil {
+		msg += " from driver type " + a.DriverType.String()
+	}
+	return msg + ": " + a.Err.Error()
+}
+
+func (a *MappingError) Unwrap() error {
+	return a.Err
+}
@@ -0,0 +1,86 @@
+package orm
+
+import (
+	"errors"
+	"testing"
+
+	mysql "github.com/go-sql-driver/mysql"
+)
+
+type sqlStateError string
+
+func (a sqlStateError) Error() string    { return "pq: " + string(a) }
+func (a sqlStateError) SQLState() string { return string(a) }
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		err  error
+		want error
+	}{
+		{&mysql.MySQLError{Number: 1062}, ErrDuplicateKey},
+		{&mysql.MySQLError{Number: 1452}, ErrForeignKeyViolation},
+		{&mysql.MySQLError{Number: 1213}, ErrDeadlock},
+		{&mysql.MySQLError{Number: 1205}, ErrLockWaitTimeout},
+		{&mysql.MySQLError{Number: 1054}, ErrUnknownColumn},
+		{&mysql.MySQLError{Number: 1064}, nil},
+		{sqlStateError("23505"), ErrDuplicateKey},
+		{sqlStateError("23503"), ErrForeignKeyViolation},
+		{sqlStateError("40P01"), ErrDeadlock},
+		{sqlStateError("55P03"), ErrLockWaitTimeout},
+		{sqlStateError("42703"), ErrUnknownColumn},
+		{sqlStateError("42601"), nil},
+		{errors.New("UNIQUE constraint failed: person.id"), ErrDuplicateKey},
+		{errors.New("FOREIGN KEY constraint failed"), ErrForeignKeyViolation},
+		{errors.New("database is locked"), ErrLockWaitTimeout},
+		{errors.New("no such column: nope"), ErrUnknownColumn},
+		{errors.New("syntax error"), nil},
+	}
+	for _, c := range cases {
+		if got := classify(c.err); got != c.want {
+			t.Errorf("classify(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestDBError(t *testing.T) {
+	if wrapDBError(nil) != nil {
+		t.Fatal("wrapDBError(nil) != nil")
+	}
+	plain := errors.New("syntax error")
+	if wrapDBError(plain) != plain {
+		t.Fatal("unclassified error must be returned as is")
+	}
+	me := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"}
+	err := wrapDBError(me)
+	if !errors.Is(err, ErrDuplicateKey) || errors.Is(err, ErrDeadlock) {
+		t.Fatalf("errors.Is(%v) mismatch", err)
+	}
+	var got *mysql.MySQLError
+	if !errors.As(err, &got) || got != me {
+		t.Fatal("driver error is not reachable by errors.As")
+	}
+	if want := "duplicate key: " + me.Error(); err.Error() != want {
+		t.Fatalf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := openTestContext(t)
+	_, err := c.Insert("person", []string{"id", "name"}, []testPerson{{ID: 1}, {ID: 1}}).Exec()
+	var de *DBError
+	if !errors.Is(err, ErrDuplicateKey) || !errors.As(err, &de) {
+		t.Errorf("duplicate insert: err = %v", err)
+	}
+	if err := c.Search("select nope from person").Result(&[]testPerson{}); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("unknown column in query: err = %v", err)
+	}
+	if _, err := c.Insert("person", []string{"nope"}, testPerson{}).Exec(); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("unknown column in struct: err = %v", err)
+	}
+	if _, err := c.Update("person", nil, "id = ?").Params(1).Exec(); !errors.Is(err, ErrNoSetColumns) {
+		t.Errorf("update without columns: err = %v", err)
+	}
+	if _, err := c.Delete("person", "").Exec(); !errors.Is(err, ErrMissingWhere) {
+		t.Errorf("delete without where: err = %v", err)
+	}
+}
@@ -1,80 +1,558 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 )
 
 type InsertContext struct {
 	err          error
-	sql          string
-	params       []interface{}
+	table        string
+	columns      []string
+	source       interface{}   // 原始的 dataset
+	query        string        // insert ... select 的查询语句
+	rows         int           // 数据行数
+	params       []interface{} // 按行排列的参数
+	batchSize    int           // 每条语句的最大行数
+	batchParams  int           // 每条语句的最大参数个数，为 0 时使用方言的限制
+	atomic       bool          // 分批执行时是否在同一个事务中
 	retLastIndex bool
+	conflict     int             // 主键或唯一键冲突时的处理方式
+	updateCols   []string        // 冲突时更新的字段
+	conflictKeys []string        // on conflict 的字段
+	fill         bool            // 将自增主键写回 dataset
+	fillErr      error           // 准备写回主键时的异常
+	targets      []reflect.Value // 写回主键的结构体
+	pkField      *fieldMeta
+	pk           string // returning 方式获取自增主键时的主键字段
 	db           *sql.DB
 	tx           *sql.Tx
+	dialect      Dialect
+	ctx          context.Context
 }
 
-func createInsertContext(db *sql.DB, tx *sql.Tx, table string, columns []string, dataset ...interface{}) *InsertContext {
+func createInsertContext(ctx context.Context, db *sql.DB, tx *sql.Tx, dialect Dialect, table string, columns []string, source interface{}) *InsertContext {
+	dataset := interfaceToArray(source)
 	if len(dataset) == 0 {
 		return &InsertContext{}
 	}
-	dataCount := len(dataset)
-	placeholder := fmt.Sprintf("(%s)", placeholder(len(columns)))
-	ps := make([]string, 0, dataCount)
-	for i := 0; i < dataCount; i++ {
-		ps = append(ps, placeholder)
-	}
-
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("insert into %s (%s) values %s", table, strings.Join(columns, ","), strings.Join(ps, ",")))
-	sql := sb.String()
-
 	params, err := ReadValue(columns, FieldMapping(dataset[0]), dataset...)
 	if err != nil {
 		return &InsertContext{err: err}
 	}
-	return &InsertContext{sql: sql, params: params, retLastIndex: false, db: db, tx: tx}
+	return &InsertContext{table: table, columns: columns, source: source, rows: len(dataset), params: params, retLastIndex: false, pk: pkOf(dataset[0]), db: db, tx: tx, dialect: dialect, ctx: ctx}
 }
 
+// 结构体的主键，未指定时为 id
+func pkOf(data interface{}) string {
+	tp := reflect.TypeOf(data)
+	if tp != nil && tp.Kind() == reflect.Ptr {
+		tp = tp.Elem()
+	}
+	if tp != nil && tp.Kind() == reflect.Struct {
+		if pk := metaOf(tp).pk; pk != "" {
+			return pk
+		}
+	}
+	return "id"
+}
+
+func createInsertFromContext(ctx context.Context, db *sql.DB, tx *sql.Tx, dialect Dialect, table string, columns []string, query *SelectContext) *InsertContext {
+	if query == nil {
+		return &InsertContext{err: errors.New("nil query")}
+	}
+	if query.err != nil {
+		return &InsertContext{err: query.err}
+	}
+	return &InsertContext{table: table, columns: columns, query: query.sql, rows: 1, params: query.params, pk: "id", db: db, tx: tx, dialect: dialect, ctx: ctx}
+}
+
+func (a *InsertContext) WithContext(ctx context.Context) *InsertContext {
+	a.ctx = ctx
+	return a
+}
+
+// Exec 返回自增主键而不是行数，拆分为多条语句时为最后一条语句的主键
+// MySQL 的 LastInsertId 为该语句插入的第一行的主键，SQLite 及 PostgreSQL(returning)为最后一行的主键
+// 需要每一行的主键时使用 FillPK
 func (a *InsertContext) LastIndex() *InsertContext {
 	a.retLastIndex = true
 	return a
 }
 
+// 返回自增主键，pk 为主键字段名
+// 仅对通过 returning 获取主键的方言(如 PostgreSQL)、FillPK 及 OnConflict 的默认字段有影响
+// 默认主键为结构体 tag 中指定的主键，未指定时为 id
+func (a *InsertContext) LastIndexOf(pk string) *InsertContext {
+	a.retLastIndex = true
+	a.pk = pk
+	if a.fill {
+		a.prepareFill()
+	}
+	return a
+}
+
+const (
+	conflictUpdate = iota + 1
+	conflictIgnore
+	conflictReplace
+)
+
+// 主键或唯一键冲突时更新 cols，cols 为空时更新除冲突字段外的所有字段
+// MySQL 生成 on duplicate key update c = values(c)，其他方言生成 on conflict (keys) do update set c = excluded.c
+// keys 默认为结构体的主键，可通过 OnConflict 指定，没有需要更新的字段时忽略冲突的行
+func (a *InsertContext) OnConflictUpdate(cols ...string) *InsertContext {
+	a.conflict = conflictUpdate
+	a.updateCols = cols
+	return a
+}
+
+// 主键或唯一键冲突时忽略该行
+// MySQL 生成 insert ignore，其他方言生成 on conflict do nothing
+func (a *InsertContext) OnConflictIgnore() *InsertContext {
+	a.conflict = conflictIgnore
+	return a
+}
+
+// 主键或唯一键冲突时删除原有的行后插入
+// MySQL 生成 replace into，SQLite 生成 insert or replace into，PostgreSQL 不支持
+func (a *InsertContext) Replace() *InsertContext {
+	if a.err != nil || a.rows == 0 {
+		return a
+	}
+	if a.dialect.Name() == PostgreSQL.Name() {
+		a.err = errors.New("replace is not supported by dialect " + a.dialect.Name() + ", use OnConflictUpdate")
+		return a
+	}
+	a.conflict = conflictReplace
+	return a
+}
+
+// on conflict 的字段，默认为结构体的主键，对 MySQL 无效
+func (a *InsertContext) OnConflict(keys ...string) *InsertContext {
+	a.conflictKeys = keys
+	return a
+}
+
+// 将自增主键写回 dataset 中每个结构体的主键字段，主键字段默认为结构体的主键，可通过 LastIndexOf 指定
+// dataset 必须是 *struct, []struct, []*struct, *[]struct 或 *[]*struct，插入的字段中包含主键时不写回
+// MySQL 通过 LastInsertId 及 auto_increment_increment 计算每一行的主键(执行前需要额外查询一次步长)，SQLite 通过 LastInsertId 计算，PostgreSQL 使用 returning
+func (a *InsertContext) FillPK() *InsertContext {
+	if a.err != nil || a.rows == 0 {
+		return a
+	}
+	a.fill = true
+	a.prepareFill()
+	return a
+}
+
+// 按当前的主键准备写回的结构体，插入的字段中包含主键时不写回
+// 异常保存在 fillErr 中，LastIndexOf 修改主键后重新准备
+func (a *InsertContext) prepareFill() {
+	a.targets, a.pkField, a.fillErr = nil, nil, nil
+	if a.insertsPK() {
+		return
+	}
+	a.fillErr = a.fillTargets()
+}
+
+// 插入的字段中是否包含主键，包含时主键由调用方指定
+func (a *InsertContext) insertsPK() bool {
+	for _, c := range a.columns {
+		if strings.EqualFold(strings.Trim(c, "`\""), a.pk) {
+			return true
+		}
+	}
+	return false
+}
+
+// 冲突时无法确定每一行的主键，不写回
+func (a *InsertContext) filling() bool {
+	return a.fill && a.pkField != nil && a.conflict == 0
+}
+
+// 准备写回主键的结构体及主键字段
+func (a *InsertContext) fillTargets() error {
+	if !a.dialect.Returning() && a.dialect.Name() != MySQL.Name() && a.dialect.Name() != SQLite.Name() {
+		return errors.New("FillPK is not supported by dialect " + a.dialect.Name())
+	}
+	targets := addressableStructs(a.source)
+	if len(targets) != a.rows {
+		return errors.New("FillPK requires dataset of *struct, []struct, []*struct, *[]struct or *[]*struct")
+	}
+	f, ok := metaOf(targets[0].Type()).columns[a.pk]
+	if !ok || f.decode == nil {
+		return fmt.Errorf("%w: primary key %s not found in %s", ErrUnknownColumn, a.pk, targets[0].Type().String())
+	}
+	a.targets, a.pkField = targets, f
+	return nil
+}
+
+// 每条语句最多插入 size 行，超出时拆分为多条语句依次执行
+// 无论是否设置，每条语句的参数个数都不会超过方言的限制(MySQL 为 65535)
+func (a *InsertContext) BatchSize(size int) *InsertContext {
+	a.batchSize = size
+	return a
+}
+
+// 每条语句最多使用 count 个参数，超出时拆分为多条语句依次执行，用于覆盖方言的限制
+func (a *InsertContext) BatchParams(count int) *InsertContext {
+	a.batchParams = count
+	return a
+}
+
+// 拆分为多条语句时在同一个事务中执行，任意一条失败时全部回滚
+// 在 TransactionContext 中总是使用当前事务
+func (a *InsertContext) Atomic() *InsertContext {
+	a.atomic = true
+	return a
+}
+
+// 返回插入的行数，拆分为多条语句时返回总行数
+// 非 Atomic 模式下某条语句失败时，返回此前已插入的行数及异常
+// LastIndex 模式下返回最后一条语句的自增主键，MySQL 为该语句第一行的主键，见 LastIndex
 func (a *InsertContext) Exec() (int64, error) {
-	if a.err != nil { // 如果构建异常，不执行
-		return 0, a.err
+	if err := a.ContextError(); err != nil { // 如果构建异常，不执行
+		return 0, err
 	}
-	if a.params == nil || len(a.params) == 0 { // 无数据，不需要执行
+	if a.rows == 0 { // 无数据，不需要执行
 		return 0, nil
 	}
+	ctx := contextOf(a.ctx)
+	if a.tx != nil || !a.atomic || a.batchRows() >= a.rows {
+		return a.exec(ctx, a.tx)
+	}
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, wrapDBError(err)
+	}
+	n, err := a.exec(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, wrapDBError(err)
+	}
+	return n, nil
+}
+
+// 按 batchRows 拆分后依次执行，tx 为 nil 时在 db 上执行
+func (a *InsertContext) exec(ctx context.Context, tx *sql.Tx) (int64, error) {
+	size := a.batchRows()
+	cols := len(a.params) / a.rows // 每一行的参数个数
+	var stat *sql.Stmt
+	statRows := 0 // stat 对应的行数，行数相同的批次复用同一个 stat
+	defer func() {
+		if stat != nil {
+			stat.Close()
+		}
+	}()
+	inc := int64(1)
+	if a.filling() && !a.returning() && a.dialect.Name() == MySQL.Name() {
+		var err error
+		if inc, err = autoIncrement(ctx, a.db, tx); err != nil {
+			return 0, err
+		}
+	}
+	var total int64
+	for start := 0; start < a.rows; start += size {
+		end := start + size
+		if end > a.rows {
+			end = a.rows
+		}
+		if stat == nil || statRows != end-start {
+			if stat != nil {
+				stat.Close()
+			}
+			var err error
+			stat, err = a.prepare(ctx, tx, a.statement(end-start))
+			if err != nil {
+				stat = nil
+				return total, err
+			}
+			statRows = end - start
+		}
+		n, ids, err := a.execBatch(ctx, stat, end-start, inc, a.params[start*cols:end*cols])
+		if err != nil {
+			return total, err
+		}
+		for i, id := range ids {
+			if err := a.pkField.decode(fieldByIndexAlloc(a.targets[start+i], a.pkField.index), id); err != nil {
+				return total, err
+			}
+		}
+		if a.retLastIndex {
+			total = n
+		} else {
+			total += n
+		}
+	}
+	return total, nil
+}
+
+func (a *InsertContext) prepare(ctx context.Context, tx *sql.Tx, query string) (*sql.Stmt, error) {
 	var stat *sql.Stmt
 	var err error
-	if a.tx == nil {
-		stat, err = a.db.Prepare(a.sql)
+	if tx == nil {
+		stat, err = a.db.PrepareContext(ctx, rebind(a.dialect, query))
 	} else {
-		stat, err = a.tx.Prepare(a.sql)
+		stat, err = tx.PrepareContext(ctx, rebind(a.dialect, query))
 	}
-	if stat != nil {
-		defer stat.Close()
+	if err != nil {
+		if stat != nil {
+			stat.Close()
+		}
+		return nil, wrapDBError(err)
+	}
+	return stat, nil
+}
+
+// 执行插入 rows 行的语句，返回行数(LastIndex 模式下为自增主键)，FillPK 模式下同时返回每一行的主键
+func (a *InsertContext) execBatch(ctx context.Context, stat *sql.Stmt, rows int, inc int64, params []interface{}) (int64, []int64, error) {
+	if a.returning() {
+		ids, err := queryIndexes(ctx, stat, params...)
+		if err != nil {
+			return 0, nil, err
+		}
+		n := int64(len(ids))
+		if a.retLastIndex {
+			n = 0
+			if len(ids) > 0 {
+				n = ids[len(ids)-1]
+			}
+		}
+		if !a.filling() {
+			return n, nil, nil
+		}
+		return n, ids, nil
 	}
+	rs, err := stat.ExecContext(ctx, params...)
 	if err != nil {
-		return 0, err
+		return 0, nil, wrapDBError(err)
+	}
+	if !a.filling() {
+		if a.retLastIndex {
+			n, err := rs.LastInsertId()
+			return n, nil, err
+		}
+		n, err := rs.RowsAffected()
+		return n, nil, err
 	}
-	rs, err := stat.Exec(a.params...)
+	last, err := rs.LastInsertId()
 	if err != nil {
-		return 0, err
+		return 0, nil, err
+	}
+	// MySQL 返回第一行的主键，SQLite 返回最后一行的主键
+	first := last
+	if a.dialect.Name() != MySQL.Name() {
+		first = last - int64(rows-1)*inc
+	}
+	ids := make([]int64, rows)
+	for i := range ids {
+		ids[i] = first + int64(i)*inc
 	}
 	if a.retLastIndex {
-		return rs.LastInsertId()
+		return last, ids, nil
+	}
+	n, err := rs.RowsAffected()
+	return n, ids, err
+}
+
+func (a *InsertContext) returning() bool {
+	return (a.retLastIndex || a.filling()) && a.dialect != nil && a.dialect.Returning()
+}
+
+// 每条语句的行数
+func (a *InsertContext) batchRows() int {
+	size := a.rows
+	if a.batchSize > 0 && a.batchSize < size {
+		size = a.batchSize
+	}
+	max := a.batchParams
+	if max <= 0 && a.dialect != nil {
+		max = a.dialect.MaxParams()
+	}
+	if cols := len(a.columns); max > 0 && cols > 0 && size*cols > max {
+		size = max / cols
+	}
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// 插入 rows 行的语句
+func (a *InsertContext) statement(rows int) string {
+	placeholder := fmt.Sprintf("(%s)", placeholder(len(a.columns)))
+	ps := make([]string, 0, rows)
+	for i := 0; i < rows; i++ {
+		ps = append(ps, placeholder)
+	}
+	isMySQL := a.dialect != nil && a.dialect.Name() == MySQL.Name()
+	insert := "insert into"
+	switch {
+	case a.conflict == conflictIgnore && isMySQL:
+		insert = "insert ignore into"
+	case a.conflict == conflictReplace && isMySQL:
+		insert = "replace into"
+	case a.conflict == conflictReplace:
+		insert = "insert or replace into"
+	}
+	query := a.query
+	if query != "" && a.dialect != nil && a.dialect.Name() == SQLite.Name() && (a.conflict == conflictUpdate || a.conflict == conflictIgnore) {
+		query = "select * from (" + query + ") where true" // SQLite 中 select 后的 on conflict 存在歧义，需要 where 子句
+	}
+	columns := strings.Join(quoteIdentifiers(a.dialect, a.columns), ",")
+	var sql string
+	switch {
+	case query == "":
+		sql = fmt.Sprintf("%s %s (%s) values %s", insert, a.table, columns, strings.Join(ps, ","))
+	case len(a.columns) == 0:
+		sql = fmt.Sprintf("%s %s %s", insert, a.table, query)
+	default:
+		sql = fmt.Sprintf("%s %s (%s) %s", insert, a.table, columns, query)
+	}
+	keys := quoteIdentifiers(a.dialect, a.upsertKeys())
+	sets := quoteIdentifiers(a.dialect, a.upsertCols())
+	for i, c := range sets {
+		if isMySQL {
+			sets[i] = c + " = values(" + c + ")"
+		} else {
+			sets[i] = c + " = excluded." + c
+		}
+	}
+	switch {
+	case a.conflict == conflictUpdate && isMySQL && len(sets) == 0: // 没有需要更新的字段，更新为原值，等同于忽略冲突
+		sql += " on duplicate key update " + keys[0] + " = " + keys[0]
+	case a.conflict == conflictUpdate && isMySQL:
+		sql += " on duplicate key update " + strings.Join(sets, ",")
+	case a.conflict == conflictUpdate && len(sets) == 0:
+		sql += " on conflict (" + strings.Join(keys, ",") + ") do nothing"
+	case a.conflict == conflictUpdate:
+		sql += " on conflict (" + strings.Join(keys, ",") + ") do update set " + strings.Join(sets, ",")
+	case a.conflict == conflictIgnore && !isMySQL:
+		sql += " on conflict"
+		if len(a.conflictKeys) > 0 {
+			sql += " (" + strings.Join(quoteIdentifiers(a.dialect, a.conflictKeys), ",") + ")"
+		}
+		sql += " do nothing"
+	}
+	if a.returning() {
+		sql += " returning " + quoteIdentifier(a.dialect, a.pk)
+	}
+	return sql
+}
+
+// 冲突时判断冲突的字段
+func (a *InsertContext) upsertKeys() []string {
+	if len(a.conflictKeys) > 0 {
+		return a.conflictKeys
+	}
+	return []string{a.pk}
+}
+
+// 冲突时更新的字段
+func (a *InsertContext) upsertCols() []string {
+	if len(a.updateCols) > 0 {
+		return a.updateCols
+	}
+	keys := a.upsertKeys()
+	cols := make([]string, 0, len(a.columns))
+	for _, c := range a.columns {
+		key := false
+		for _, k := range keys {
+			if strings.EqualFold(strings.Trim(c, "`\""), strings.Trim(k, "`\"")) {
+				key = true
+				break
+			}
+		}
+		if !key {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+// 通过 returning 子句获取每一行的主键
+func queryIndexes(ctx context.Context, stat *sql.Stmt, params ...interface{}) ([]int64, error) {
+	rows, err := stat.QueryContext(ctx, params...)
+	if rows != nil {
+		defer rows.Close()
+	}
+	if err != nil {
+		return nil, wrapDBError(err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, wrapDBError(err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, wrapDBError(rows.Err())
+}
+
+// MySQL 自增主键的步长
+func autoIncrement(ctx context.Context, db *sql.DB, tx *sql.Tx) (int64, error) {
+	var row *sql.Row
+	if tx == nil {
+		row = db.QueryRowContext(ctx, "select @@auto_increment_increment")
+	} else {
+		row = tx.QueryRowContext(ctx, "select @@auto_increment_increment")
 	}
-	return rs.RowsAffected()
+	var inc int64
+	if err := row.Scan(&inc); err != nil {
+		return 0, wrapDBError(err)
+	}
+	return inc, nil
+}
+
+// 可以写入的结构体，source 中包含无法写入的数据(如 []struct 以外的结构体值)时返回 nil
+func addressableStructs(source interface{}) []reflect.Value {
+	v := reflect.ValueOf(source)
+	if v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Slice {
+		v = v.Elem()
+	}
+	var vs []reflect.Value
+	switch v.Kind() {
+	case reflect.Ptr:
+		vs = []reflect.Value{v}
+	case reflect.Slice:
+		vs = make([]reflect.Value, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			vs = append(vs, v.Index(i))
+		}
+	default:
+		return nil
+	}
+	rs := make([]reflect.Value, 0, len(vs))
+	for _, e := range vs {
+		if e.Kind() == reflect.Ptr {
+			if e.IsNil() {
+				return nil
+			}
+			e = e.Elem()
+		}
+		if e.Kind() != reflect.Struct || !e.CanSet() {
+			return nil
+		}
+		rs = append(rs, e)
+	}
+	return rs
 }
 
 // 返回 InsertContext 构建过程中的异常
 func (a *InsertContext) ContextError() error {
-	return a.err
+	if a.err != nil {
+		return a.err
+	}
+	return a.fillErr
 }
 
 func placeholder(count int) string {
@@ -85,7 +563,10 @@ func placeholder(count int) string {
 	return strings.Join(s, ",")
 }
 
-// 返回语句和参数
+// 返回语句和参数，拆分为多条语句执行时返回的是未拆分的语句
 func (a *InsertContext) Desc() (string, []interface{}) {
-	return a.sql, a.params
+	if a.rows == 0 {
+		return "", a.params
+	}
+	return rebind(a.dialect, a.statement(a.rows)), a.params
 }
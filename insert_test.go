@@ -0,0 +1,302 @@
+package orm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFillPK(t *testing.T) {
+	c := openTestContext(t)
+	ps := []testPerson{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	if _, err := c.Insert("person", []string{"name"}, ps).FillPK().BatchSize(2).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range ps {
+		if p.ID != int64(i+1) {
+			t.Fatalf("ps[%d].ID = %d, want %d", i, p.ID, i+1)
+		}
+	}
+
+	p := &testPerson{Name: "d"}
+	if _, err := c.InsertModel(p).FillPK().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != 4 {
+		t.Fatalf("ID = %d, want 4", p.ID)
+	}
+}
+
+func TestFillPKWithInsertedPK(t *testing.T) {
+	c := openTestContext(t)
+	ps := []testPerson{{ID: 10, Name: "a"}, {ID: 5, Name: "b"}}
+	ic := c.Insert("person", []string{"id", "name"}, ps).FillPK()
+	if err := ic.ContextError(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ic.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if ps[0].ID != 10 || ps[1].ID != 5 {
+		t.Fatalf("IDs = %d, %d, want 10, 5", ps[0].ID, ps[1].ID)
+	}
+}
+
+func TestFillPKRequiresAddressableDataset(t *testing.T) {
+	c := openTestContext(t)
+	if err := c.Insert("person", []string{"name"}, testPerson{Name: "a"}).FillPK().ContextError(); err == nil {
+		t.Fatal("want error for struct value")
+	}
+}
+
+type pkPerson struct {
+	PID  int64  `column:"pid,pk"`
+	Name string `column:"name"`
+}
+
+func (pkPerson) TableName() string { return "pkp" }
+
+// 主键为 pid 的 pkp 表
+func createPKTable(t *testing.T, c *Context) {
+	t.Helper()
+	if _, err := c.db.Exec(`create table pkp (pid integer primary key autoincrement, name text unique)`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 默认使用结构体 tag 中指定的主键
+func TestFillPKUsesStructPK(t *testing.T) {
+	c := openTestContext(t)
+	createPKTable(t, c)
+	ps := []*pkPerson{{Name: "a"}, {Name: "b"}}
+	if _, err := c.Insert("pkp", []string{"name"}, ps).FillPK().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if ps[0].PID != 1 || ps[1].PID != 2 {
+		t.Fatalf("PIDs = %d, %d, want 1, 2", ps[0].PID, ps[1].PID)
+	}
+	if sql, _ := newTestInsertOf(PostgreSQL, []string{"name"}, ps).LastIndex().Desc(); sql != `insert into pkp ("name") values ($1),($2) returning "pid"` {
+		t.Fatalf("returning = %s", sql)
+	}
+	// LastIndexOf 可以在 FillPK 之后调用
+	r := &struct {
+		PID  int64  `column:"pid"`
+		Name string `column:"name"`
+	}{Name: "c"}
+	ic := c.Insert("pkp", []string{"name"}, r).FillPK()
+	if ic.ContextError() == nil {
+		t.Fatal("want error for pk id not found")
+	}
+	if _, err := ic.LastIndexOf("pid").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if r.PID != 3 {
+		t.Fatalf("PID = %d, want 3", r.PID)
+	}
+}
+
+func newTestInsert(d Dialect, columns []string, rows int) *InsertContext {
+	return newTestInsertOf(d, columns, make([]testPerson, rows))
+}
+
+func newTestInsertOf(d Dialect, columns []string, dataset interface{}) *InsertContext {
+	table := "person"
+	if m, err := modelOf(dataset); err == nil {
+		table = m.table
+	}
+	return createInsertContext(nil, nil, nil, d, table, columns, dataset)
+}
+
+func TestInsertStatement(t *testing.T) {
+	cols := []string{"id", "name"}
+	cases := []struct {
+		name string
+		ic   *InsertContext
+		want string
+	}{
+		{"mysql", newTestInsert(MySQL, cols, 2),
+			"insert into person (`id`,`name`) values (?,?),(?,?)"},
+		{"postgres", newTestInsert(PostgreSQL, cols, 2),
+			`insert into person ("id","name") values ($1,$2),($3,$4)`},
+		{"postgres returning", newTestInsert(PostgreSQL, []string{"name"}, 2).LastIndex(),
+			`insert into person ("name") values ($1),($2) returning "id"`},
+	}
+	for _, c := range cases {
+		if err := c.ic.ContextError(); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got, _ := c.ic.Desc(); got != c.want {
+			t.Errorf("%s:\n got %s\nwant %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestInsertFromStatement(t *testing.T) {
+	q := createSelectContext(nil, nil, nil, SQLite, "person", []string{"id", "name"}, "age > ?", 18)
+	ic := createInsertFromContext(nil, nil, nil, SQLite, "archive", []string{"id", "name"}, q)
+	sql, params := ic.Desc()
+	if want := `insert into archive ("id","name") select id,name from person where age > ?`; sql != want {
+		t.Errorf("got %s, want %s", sql, want)
+	}
+	if len(params) != 1 || params[0] != 18 {
+		t.Errorf("params = %v", params)
+	}
+	sql, _ = ic.OnConflictIgnore().Desc()
+	if want := `insert into archive ("id","name") select * from (select id,name from person where age > ?) where true on conflict do nothing`; sql != want {
+		t.Errorf("got %s, want %s", sql, want)
+	}
+}
+
+func TestInsertFrom(t *testing.T) {
+	c := openTestContext(t)
+	if _, err := c.db.Exec(`create table archive (id integer primary key, name text)`); err != nil {
+		t.Fatal(err)
+	}
+	insertNames(t, c, "a", "b", "c")
+	n, err := c.InsertFrom("archive", []string{"id", "name"}, c.Select("person", []string{"id", "name"}, "age > ?", 1)).Exec()
+	if err != nil || n != 2 {
+		t.Fatalf("InsertFrom = %d, %v", n, err)
+	}
+	tx, err := c.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err = tx.InsertFrom("archive", nil, tx.Select("person", []string{"id", "name"}, "age = ?", 1)).Exec()
+	if err != nil || n != 1 {
+		tx.Rollback()
+		t.Fatalf("tx InsertFrom = %d, %v", n, err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	n, err = c.InsertFrom("archive", nil, c.Select("person", []string{"id", "name"}, "")).OnConflictIgnore().Exec()
+	if err != nil || n != 0 {
+		t.Fatalf("OnConflictIgnore = %d, %v", n, err)
+	}
+	if _, err := c.InsertFrom("archive", nil, nil).Exec(); err == nil {
+		t.Fatal("want error for nil query")
+	}
+	if _, err := c.InsertFrom("archive", nil, c.Search("select 1").Limit(0, 1)).Exec(); err == nil {
+		t.Fatal("want error for invalid query")
+	}
+}
+
+func TestInsertBatchRows(t *testing.T) {
+	cases := []struct {
+		name string
+		ic   *InsertContext
+		want int
+	}{
+		{"all rows", newTestInsert(MySQL, []string{"id", "name"}, 100), 100},
+		{"batch size", newTestInsert(MySQL, []string{"id", "name"}, 100).BatchSize(30), 30},
+		{"batch params", newTestInsert(MySQL, []string{"id", "name"}, 100).BatchParams(11), 5},
+		{"dialect limit", newTestInsert(SQLite, []string{"id", "name", "age"}, 20000), 32766 / 3},
+		{"at least one row", newTestInsert(MySQL, []string{"id", "name"}, 3).BatchParams(1), 1},
+	}
+	for _, c := range cases {
+		if got := c.ic.batchRows(); got != c.want {
+			t.Errorf("%s: batchRows() = %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestInsertBatches(t *testing.T) {
+	c := openTestContext(t)
+	ps := make([]testPerson, 25)
+	n, err := c.Insert("person", []string{"name"}, ps).BatchSize(10).Atomic().Exec()
+	if err != nil || n != 25 {
+		t.Fatalf("Exec() = %d, %v", n, err)
+	}
+	// 第二批主键冲突时全部回滚
+	dup := []testPerson{{ID: 100}, {ID: 101}, {ID: 100}}
+	if _, err := c.Insert("person", []string{"id"}, dup).BatchSize(2).Atomic().Exec(); !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("err = %v, want ErrDuplicateKey", err)
+	}
+	var count int
+	if err := c.Select("person", []string{"count(1)"}, "id >= 100").Result(&count); err != nil || count != 0 {
+		t.Fatalf("count = %d, %v", count, err)
+	}
+}
+
+// 拆分为多条语句时返回最后一条语句的自增主键，SQLite 为最后一行
+func TestInsertBatchesLastIndex(t *testing.T) {
+	c := openTestContext(t)
+	id, err := c.Insert("person", []string{"name"}, make([]testPerson, 25)).BatchSize(10).LastIndex().Exec()
+	if err != nil || id != 25 {
+		t.Fatalf("Exec() = %d, %v", id, err)
+	}
+}
+
+func TestUpsertStatement(t *testing.T) {
+	cols := []string{"id", "name"}
+	pks := []pkPerson{{PID: 1, Name: "a"}}
+	cases := []struct {
+		name string
+		ic   *InsertContext
+		want string
+	}{
+		{"mysql update", newTestInsert(MySQL, cols, 1).OnConflictUpdate(),
+			"insert into person (`id`,`name`) values (?,?) on duplicate key update `name` = values(`name`)"},
+		{"mysql ignore", newTestInsert(MySQL, cols, 1).OnConflictIgnore(),
+			"insert ignore into person (`id`,`name`) values (?,?)"},
+		{"mysql replace", newTestInsert(MySQL, cols, 1).Replace(),
+			"replace into person (`id`,`name`) values (?,?)"},
+		{"postgres update", newTestInsert(PostgreSQL, cols, 1).OnConflict("name").OnConflictUpdate("id"),
+			`insert into person ("id","name") values ($1,$2) on conflict ("name") do update set "id" = excluded."id"`},
+		{"postgres ignore", newTestInsert(PostgreSQL, cols, 1).OnConflictIgnore(),
+			`insert into person ("id","name") values ($1,$2) on conflict do nothing`},
+		{"sqlite replace", newTestInsert(SQLite, cols, 1).Replace(),
+			`insert or replace into person ("id","name") values (?,?)`},
+		// 默认使用结构体的主键
+		{"postgres struct pk", newTestInsertOf(PostgreSQL, []string{"pid", "name"}, pks).OnConflictUpdate(),
+			`insert into pkp ("pid","name") values ($1,$2) on conflict ("pid") do update set "name" = excluded."name"`},
+		// 所有字段都是冲突字段时没有需要更新的字段
+		{"mysql nothing to update", newTestInsert(MySQL, []string{"id"}, 1).OnConflictUpdate(),
+			"insert into person (`id`) values (?) on duplicate key update `id` = `id`"},
+		{"postgres nothing to update", newTestInsertOf(PostgreSQL, []string{"pid"}, pks).OnConflictUpdate(),
+			`insert into pkp ("pid") values ($1) on conflict ("pid") do nothing`},
+	}
+	for _, c := range cases {
+		if err := c.ic.ContextError(); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got, _ := c.ic.Desc(); got != c.want {
+			t.Errorf("%s:\n got %s\nwant %s", c.name, got, c.want)
+		}
+	}
+	if err := newTestInsert(PostgreSQL, cols, 1).Replace().ContextError(); err == nil {
+		t.Error("postgres replace: want error")
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	c := openTestContext(t)
+	createPKTable(t, c)
+	ps := []pkPerson{{PID: 1, Name: "a"}, {PID: 2, Name: "b"}}
+	if _, err := c.Insert("pkp", []string{"pid", "name"}, ps).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	ps[0].Name = "aa"
+	if _, err := c.Insert("pkp", []string{"pid", "name"}, ps).OnConflictUpdate().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Insert("pkp", []string{"pid"}, ps).OnConflictUpdate().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.Insert("pkp", []string{"pid", "name"}, []pkPerson{{PID: 3, Name: "b"}, {PID: 4, Name: "d"}}).OnConflictIgnore().Exec(); err != nil || n != 1 {
+		t.Fatalf("OnConflictIgnore = %d, %v", n, err)
+	}
+	if _, err := c.Insert("pkp", []string{"pid", "name"}, pkPerson{PID: 5, Name: "d"}).OnConflict("name").OnConflictUpdate("pid").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Insert("pkp", []string{"pid", "name"}, pkPerson{PID: 2, Name: "bb"}).Replace().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	if err := c.Search("select pid || name from pkp order by pid").Result(&names); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "1aa,2bb,5d" {
+		t.Fatalf("rows = %v", names)
+	}
+}
@@ -0,0 +1,201 @@
+package orm
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// 结构体字段的元数据
+type fieldMeta struct {
+	name    string // 字段名，嵌套的字段为 A.B
+	column  string
+	index   []int
+	typ     reflect.Type
+	options []string
+	decode  decoder // 将查询结果写入字段
+	encode  encoder // 将字段转换为参数，为 nil 时直接使用字段值
+}
+
+// 结构体的元数据，按类型缓存
+type structMeta struct {
+	typ      reflect.Type
+	table    string // TableName 或 table tag，可能为空
+	pk       string // pk 选项指定的主键，未指定时为 id 字段，可能为空
+	fields   []*fieldMeta
+	columns  map[string]*fieldMeta
+	snapshot []int // 嵌入的 Snapshot 的位置，没有时为 nil
+}
+
+var metaCache sync.Map // reflect.Type -> *structMeta
+
+// 具名结构体字段展开时的 column 前缀，如 Address Address `prefix:"addr_"`
+const prefixTag = "prefix"
+
+// tp 必须是 struct 类型
+func metaOf(tp reflect.Type) *structMeta {
+	if m, ok := metaCache.Load(tp); ok {
+		return m.(*structMeta)
+	}
+	m, _ := metaCache.LoadOrStore(tp, newStructMeta(tp))
+	return m.(*structMeta)
+}
+
+// 自定义 tag 后需要重新解析
+func resetMetaCache() {
+	metaCache.Range(func(k, _ interface{}) bool {
+		metaCache.Delete(k)
+		return true
+	})
+}
+
+func newStructMeta(tp reflect.Type) *structMeta {
+	m := &structMeta{typ: tp, columns: make(map[string]*fieldMeta)}
+	if t, ok := reflect.New(tp).Interface().(Table); ok {
+		m.table = t.TableName()
+	}
+	var candidates []*fieldMeta
+	m.collect(tp, nil, "", "", map[reflect.Type]bool{tp: true}, &candidates)
+	// 同名 column 取层级最浅的字段，层级相同时取先定义的
+	for _, f := range candidates {
+		if e, ok := m.columns[f.column]; !ok || len(f.index) < len(e.index) {
+			m.columns[f.column] = f
+		}
+	}
+	for _, f := range candidates {
+		if m.columns[f.column] != f {
+			continue
+		}
+		m.fields = append(m.fields, f)
+		if m.pk == "" && f.hasOption("pk") {
+			m.pk = f.column
+		}
+	}
+	if _, ok := m.columns["id"]; ok && m.pk == "" {
+		m.pk = "id"
+	}
+	return m
+}
+
+// 收集 tp 的字段，匿名嵌入的结构体(或其指针)以及带 prefix tag 的结构体字段会被展开
+// index 为 tp 在最外层结构体中的位置，prefix 为 column 前缀，visiting 用于避免循环嵌套
+func (a *structMeta) collect(tp reflect.Type, index []int, prefix, namePrefix string, visiting map[reflect.Type]bool, rs *[]*fieldMeta) {
+	for i := 0; i < tp.NumField(); i++ {
+		f := tp.Field(i)
+		if a.table == "" {
+			a.table = strings.TrimSpace(f.Tag.Get(tableTag))
+		}
+		fi := make([]int, len(index)+1)
+		copy(fi, index)
+		fi[len(index)] = i
+		if f.Anonymous && f.Type == type_snapshot {
+			if a.snapshot == nil {
+				a.snapshot = fi
+			}
+			continue
+		}
+		column, options := parseTag(f)
+		if column == "" {
+			if nested, p, ok := nestedStruct(f); ok && !visiting[nested] {
+				visiting[nested] = true
+				a.collect(nested, fi, prefix+p, namePrefix+f.Name+".", visiting, rs)
+				delete(visiting, nested)
+			}
+			continue
+		}
+		if f.PkgPath != "" { // 未导出
+			continue
+		}
+		fm := &fieldMeta{name: namePrefix + f.Name, column: prefix + column, index: fi, typ: f.Type, options: options}
+		if fm.hasOption("json") {
+			fm.decode, fm.encode = decodeJSON, encodeJSON
+		} else {
+			fm.decode, fm.encode = decoderOf(f.Type), encoderOf(f.Type)
+		}
+		*rs = append(*rs, fm)
+	}
+}
+
+// 需要展开的结构体字段及其 column 前缀
+// 匿名嵌入的结构体/结构体指针总是展开，具名的结构体字段需要通过 prefix tag 指定前缀
+func nestedStruct(f reflect.StructField) (reflect.Type, string, bool) {
+	ft := f.Type
+	if ft.Kind() == reflect.Ptr {
+		if f.PkgPath != "" { // 未导出的指针无法初始化
+			return nil, "", false
+		}
+		ft = ft.Elem()
+	}
+	if ft.Kind() != reflect.Struct || decoderOf(ft) != nil { // 如 time.Time
+		return nil, "", false
+	}
+	prefix, ok := f.Tag.Lookup(prefixTag)
+	if !f.Anonymous && (!ok || f.PkgPath != "") {
+		return nil, "", false
+	}
+	return ft, strings.TrimSpace(prefix), true
+}
+
+// 按 index 获取字段，路径上有 nil 指针时返回 false
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// 按 index 获取字段，路径上的 nil 指针会被初始化
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func (a *fieldMeta) hasOption(option string) bool {
+	for _, o := range a.options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// 所有的 column，按字段定义的顺序
+func (a *structMeta) columnNames() []string {
+	rs := make([]string, 0, len(a.fields))
+	for _, f := range a.fields {
+		rs = append(rs, f.column)
+	}
+	return rs
+}
+
+// t 可以是 struct，*struct 或者它们的 reflect.Type，其他类型返回 nil
+func metaOfValue(t interface{}) *structMeta {
+	tp, ok := t.(reflect.Type)
+	if !ok {
+		tp = reflect.TypeOf(t)
+	}
+	if tp == nil {
+		return nil
+	}
+	if tp.Kind() == reflect.Ptr {
+		tp = tp.Elem()
+	}
+	if tp.Kind() != reflect.Struct {
+		return nil
+	}
+	return metaOf(tp)
+}
@@ -0,0 +1,146 @@
+package orm
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type benchPerson struct {
+	ID       int64     `column:"id"`
+	Name     string    `column:"name"`
+	Age      int       `column:"age"`
+	Email    string    `column:"email"`
+	Birthday time.Time `column:"birthday"`
+}
+
+const benchRows = 10000
+
+var benchColumns = []string{"id", "name", "age", "email", "birthday"}
+
+// 包含 benchRows 行数据的 bench 表，字符串使用 blob 保存，与 MySQL 文本协议一样返回 []byte
+func openBenchContext(b *testing.B) *Context {
+	b.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		b.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`create table bench (id integer primary key, name blob, age int, email blob, birthday datetime)`); err != nil {
+		b.Fatal(err)
+	}
+	if err := RegisterDB(b.Name(), db, SQLite); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { UnregisterDatasource(b.Name()) })
+	c, _ := CreateContextOf(b.Name())
+	now := time.Now()
+	ps := make([]benchPerson, benchRows)
+	for i := range ps {
+		ps[i] = benchPerson{ID: int64(i + 1), Name: "name", Age: i % 100, Email: "user@example.com", Birthday: now}
+	}
+	if _, err := c.Insert("bench", benchColumns, ps).Atomic().Exec(); err != nil {
+		b.Fatal(err)
+	}
+	return c
+}
+
+func TestMetaNestedAndDuplicate(t *testing.T) {
+	type base struct {
+		ID   int64  `column:"id,pk"`
+		Name string `column:"name"`
+	}
+	type address struct {
+		City string `column:"city"`
+	}
+	type user struct {
+		base
+		Name string   `column:"name"` // 层级更浅的字段优先
+		Addr address  `prefix:"addr_"`
+		_    struct{} `table:"users"`
+	}
+	m := metaOf(reflect.TypeOf(user{}))
+	if m.table != "users" || m.pk != "id" {
+		t.Fatalf("table = %q, pk = %q", m.table, m.pk)
+	}
+	if got := m.columnNames(); !reflect.DeepEqual(got, []string{"id", "name", "addr_city"}) {
+		t.Fatalf("columns = %v", got)
+	}
+	if f := m.columns["name"]; f.name != "Name" || len(f.index) != 1 {
+		t.Fatalf("name field = %+v", f)
+	}
+	if f := m.columns["addr_city"]; f.name != "Addr.City" {
+		t.Fatalf("addr_city field = %+v", f)
+	}
+}
+
+type EmbedBase struct {
+	ID   int64  `column:"id,pk"`
+	Name string `column:"name"`
+}
+
+type embedPerson struct {
+	*EmbedBase
+	Age int `column:"age"`
+}
+
+// 嵌入的结构体指针为 nil 时插入 null，查询时自动创建
+func TestEmbeddedPointer(t *testing.T) {
+	c := openTestContext(t)
+	ps := []embedPerson{{Age: 1}, {EmbedBase: &EmbedBase{ID: 2, Name: "b"}, Age: 2}}
+	if _, err := c.Insert("person", []string{"id", "name", "age"}, ps).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	var rs []*embedPerson
+	if err := c.Select("person", ColumnsExcept(embedPerson{}), "").OrderByAsc("id").Result(&rs); err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 || rs[0].EmbedBase == nil || rs[0].Name != "" || rs[1].ID != 2 || rs[1].Name != "b" || rs[1].Age != 2 {
+		t.Fatalf("rows = %+v", rs)
+	}
+}
+
+// 查询 10k 行并写入结构体数组
+func BenchmarkResult(b *testing.B) {
+	c := openBenchContext(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var rs []benchPerson
+		if err := c.Select("bench", benchColumns, "").Result(&rs); err != nil {
+			b.Fatal(err)
+		}
+		if len(rs) != benchRows {
+			b.Fatalf("rows = %d", len(rs))
+		}
+	}
+}
+
+func BenchmarkResultPtr(b *testing.B) {
+	c := openBenchContext(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var rs []*benchPerson
+		if err := c.Select("bench", benchColumns, "").Result(&rs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// 读取 10k 个结构体的参数
+func BenchmarkReadValue(b *testing.B) {
+	dataset := make([]*benchPerson, benchRows)
+	for i := range dataset {
+		dataset[i] = &benchPerson{ID: int64(i), Name: "name", Age: i % 100, Email: "user@example.com", Birthday: time.Now()}
+	}
+	fn := FieldMapping(benchPerson{})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadValue(benchColumns, fn, interfaceToArray(dataset)...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package orm
+
+import (
+	"errors"
+	"reflect"
+)
+
+// 实现了 Table 的结构体使用 TableName 作为表名
+// 也可以在任意字段上通过 table tag 指定表名，如
+//
+//	type Person struct {
+//		_    struct{} `table:"person"`
+//		ID   int64    `column:"id,pk"`
+//		Name string   `column:"user_name"`
+//	}
+//
+// 主键通过 column tag 的 pk 选项指定，未指定时使用名为 id 的字段
+type Table interface {
+	TableName() string
+}
+
+const tableTag = "table"
+
+type model struct {
+	table   string
+	pk      string
+	columns []string
+}
+
+// data 可以是 struct, *struct 或它们的数组/数组指针
+func modelOf(data interface{}) (*model, error) {
+	if data == nil {
+		return nil, errors.New("nil model")
+	}
+	tp := reflect.TypeOf(data)
+	for tp.Kind() == reflect.Ptr || tp.Kind() == reflect.Slice || tp.Kind() == reflect.Array {
+		tp = tp.Elem()
+	}
+	if tp.Kind() != reflect.Struct {
+		return nil, errors.New("model must be struct, provided type is " + tp.String())
+	}
+	meta := metaOf(tp)
+	if meta.table == "" {
+		return nil, errors.New("can not find table name of " + tp.String() + ", implement Table or add tag(" + tableTag + ")")
+	}
+	if meta.pk == "" {
+		return nil, errors.New("can not find primary key of " + tp.String() + ", add option pk to tag(" + tag + ")")
+	}
+	return &model{table: meta.table, pk: meta.pk, columns: meta.columnNames()}, nil
+}
+
+// 除主键外的所有字段
+func (a *model) columnsExceptPK() []string {
+	rs := make([]string, 0, len(a.columns))
+	for _, c := range a.columns {
+		if c != a.pk {
+			rs = append(rs, c)
+		}
+	}
+	return rs
+}
+
+// pk = ?
+func (a *model) wherePK(d Dialect) string {
+	return quoteIdentifier(d, a.pk) + " = ?"
+}
+
+// 主键是否全部为零值，零值时由数据库生成，部分为零值时返回异常
+func (a *model) zeroPK(data []interface{}) (bool, error) {
+	rs, err := ReadValue([]string{a.pk}, FieldMapping(data[0]), data...)
+	if err != nil {
+		return false, err
+	}
+	zero := func(v interface{}) bool {
+		return v == nil || reflect.ValueOf(v).IsZero()
+	}
+	for _, v := range rs[1:] {
+		if zero(v) != zero(rs[0]) {
+			return false, errors.New("primary key " + a.pk + " must be either zero or non-zero in all rows")
+		}
+	}
+	return zero(rs[0]), nil
+}
+
+// Context 与 TransactionContext 共有的构建方法
+type builder interface {
+	Dialect() Dialect
+	Insert(table string, columns []string, dataset interface{}) *InsertContext
+	Delete(table string, where string) *DeleteContext
+	Update(table string, setCols []string, where string) *UpdateContext
+	Select(table string, columns []string, where string, params ...interface{}) *SelectContext
+}
+
+func insertModel(b builder, dataset interface{}) *InsertContext {
+	m, err := modelOf(dataset)
+	if err != nil {
+		return &InsertContext{err: err}
+	}
+	data := interfaceToArray(dataset)
+	if len(data) == 0 {
+		return &InsertContext{}
+	}
+	zero, err := m.zeroPK(data)
+	if err != nil {
+		return &InsertContext{err: err}
+	}
+	columns := m.columns
+	if zero {
+		columns = m.columnsExceptPK()
+	}
+	return b.Insert(quoteIdentifier(b.Dialect(), m.table), columns, dataset)
+}
+
+func updateModel(b builder, data interface{}, cols ...string) *UpdateContext {
+	m, err := modelOf(data)
+	if err != nil {
+		return &UpdateContext{err: err}
+	}
+	if len(cols) == 0 {
+		cols = m.columnsExceptPK()
+	}
+	return b.Update(quoteIdentifier(b.Dialect(), m.table), cols, m.wherePK(b.Dialect())).ReflectParamsFrom(data, []string{m.pk})
+}
+
+func deleteModel(b builder, data interface{}) *DeleteContext {
+	m, err := modelOf(data)
+	if err != nil {
+		return &DeleteContext{err: err}
+	}
+	return b.Delete(quoteIdentifier(b.Dialect(), m.table), m.wherePK(b.Dialect())).ReflectParamsFrom(data, []string{m.pk})
+}
+
+func selectByPK(b builder, result interface{}, id interface{}) *SelectContext {
+	m, err := modelOf(result)
+	if err != nil {
+		return &SelectContext{err: err}
+	}
+	return b.Select(quoteIdentifier(b.Dialect(), m.table), quoteIdentifiers(b.Dialect(), m.columns), m.wherePK(b.Dialect()), id)
+}
+
+// 插入 struct, *struct 或它们的数组，主键为零值时不插入主键字段，数组中的主键必须全部为零值或全部不为零值
+// 需要将自增主键写回 dataset 时调用 FillPK，如 InsertModel(&p).FillPK().Exec()
+func (a *Context) InsertModel(dataset interface{}) *InsertContext {
+	return insertModel(a, dataset)
+}
+
+// 按主键更新，cols 为空时更新除主键外的所有字段
+func (a *Context) UpdateModel(data interface{}, cols ...string) *UpdateContext {
+	return updateModel(a, data, cols...)
+}
+
+// 按主键删除
+func (a *Context) DeleteModel(data interface{}) *DeleteContext {
+	return deleteModel(a, data)
+}
+
+// 按主键查询，result 必须是结构体的指针，未查询到数据时返回 ErrNoRows
+func (a *Context) FindByPK(result interface{}, id interface{}) error {
+	return selectByPK(a, result, id).One().Result(result)
+}
+
+func (a *TransactionContext) InsertModel(dataset interface{}) *InsertContext {
+	return insertModel(a, dataset)
+}
+
+func (a *TransactionContext) UpdateModel(data interface{}, cols ...string) *UpdateContext {
+	return updateModel(a, data, cols...)
+}
+
+func (a *TransactionContext) DeleteModel(data interface{}) *DeleteContext {
+	return deleteModel(a, data)
+}
+
+func (a *TransactionContext) FindByPK(result interface{}, id interface{}) error {
+	return selectByPK(a, result, id).One().Result(result)
+}
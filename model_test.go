@@ -0,0 +1,73 @@
+package orm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestModelCRUD(t *testing.T) {
+	c := openTestContext(t)
+	if _, err := c.InsertModel([]*testPerson{{Name: "a", Age: 1}, {Name: "b", Age: 2}}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	// 主键不为零值时插入主键
+	if _, err := c.InsertModel(testPerson{ID: 10, Name: "c", Age: 3}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	var p testPerson
+	if err := c.FindByPK(&p, 10); err != nil || p.Name != "c" {
+		t.Fatalf("FindByPK = %+v, %v", p, err)
+	}
+	p.Name, p.Age = "cc", 30
+	if n, err := c.UpdateModel(&p, "name").Exec(); err != nil || n != 1 {
+		t.Fatalf("UpdateModel = %d, %v", n, err)
+	}
+	var q testPerson
+	if err := c.FindByPK(&q, 10); err != nil || q.Name != "cc" || q.Age != 3 {
+		t.Fatalf("after UpdateModel = %+v, %v", q, err)
+	}
+	if n, err := c.UpdateModel(&p).Exec(); err != nil || n != 1 {
+		t.Fatalf("UpdateModel all columns = %d, %v", n, err)
+	}
+	if n, err := c.DeleteModel(p).Exec(); err != nil || n != 1 {
+		t.Fatalf("DeleteModel = %d, %v", n, err)
+	}
+	if err := c.FindByPK(&q, 10); !errors.Is(err, ErrNoRows) {
+		t.Fatalf("err = %v, want ErrNoRows", err)
+	}
+}
+
+func TestInsertModelMixedPK(t *testing.T) {
+	c := openTestContext(t)
+	ps := []testPerson{{Name: "a"}, {ID: 5, Name: "b"}}
+	if _, err := c.InsertModel(ps).Exec(); err == nil {
+		t.Fatal("want error for mixed primary keys")
+	}
+	ps = []testPerson{{ID: 5, Name: "a"}, {ID: 6, Name: "b"}}
+	if _, err := c.InsertModel(ps).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	if err := c.Select("person", []string{"id"}, "").OrderByAsc("id").Result(&ids); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 5 || ids[1] != 6 {
+		t.Fatalf("ids = %v", ids)
+	}
+}
+
+func TestModelErrors(t *testing.T) {
+	c := openTestContext(t)
+	type noTable struct {
+		ID int64 `column:"id"`
+	}
+	if _, err := c.InsertModel(noTable{}).Exec(); err == nil {
+		t.Error("want error without table name")
+	}
+	if _, err := c.InsertModel(1).Exec(); err == nil {
+		t.Error("want error for non-struct model")
+	}
+	if err := c.FindByPK(nil, 1); err == nil {
+		t.Error("want error for nil model")
+	}
+}
@@ -0,0 +1,43 @@
+package orm
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type testPerson struct {
+	ID       int64      `column:"id,pk"`
+	Name     string     `column:"name"`
+	Age      int        `column:"age"`
+	Nick     *string    `column:"nick"`
+	Birthday *time.Time `column:"birthday"`
+}
+
+func (testPerson) TableName() string { return "person" }
+
+// 内存中的 SQLite 数据源，包含 person 表
+func openTestContext(t testing.TB) *Context {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1) // 每个连接都是独立的内存数据库
+	_, err = db.Exec(`create table person (id integer primary key autoincrement, name text, age int, nick text, birthday datetime)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := t.Name()
+	if err := RegisterDB(name, db, SQLite); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { UnregisterDatasource(name) })
+	c, err := CreateContextOf(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
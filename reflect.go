@@ -2,10 +2,12 @@ package orm
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,6 +16,16 @@ var tag string = "column"
 // 自定义tag标签
 func CustomTag(t string) {
 	tag = t
+	resetMetaCache()
+}
+
+// tag 格式为 "column_name,option1,option2"，如 `column:"id,pk"`
+func parseTag(f reflect.StructField) (string, []string) {
+	parts := strings.Split(f.Tag.Get(tag), ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts[0], parts[1:]
 }
 
 // 将 i 转为 数组形式的 interface{}
@@ -45,7 +57,9 @@ func interfaceToArray(i interface{}) []interface{} {
 }
 
 // columns 结构体对应的 column tag
-// fn fieldMapping    column -> field name
+// 指针字段为 nil 或 sql.Null* 字段 Valid 为 false 时对应的参数为 NULL
+// 实现了 driver.Valuer 或注册了 Converter 的字段会先转换为驱动支持的类型
+// fn fieldMapping    column -> field name，仅用于 tag 中不存在的 column
 // dataset 可以是指针数组，也可以是结构体数组  但不能是空数据
 // 1. []struct
 // 2. []*struct
@@ -55,300 +69,376 @@ func ReadValue(columns []string, fn map[string]string, dataset ...interface{}) (
 	if len(dataset) == 0 {
 		return nil, errors.New("empty dataset")
 	}
-	if reflect.TypeOf(dataset[0]).Kind() == reflect.Ptr {
-		return readValueOfPtr(columns, fn, dataset...)
-	} else if reflect.TypeOf(dataset[0]).Kind() == reflect.Struct {
-		return readValueOfStruct(columns, fn, dataset...)
+	tp := reflect.TypeOf(dataset[0])
+	if tp == nil {
+		return nil, errors.New("nil data")
 	}
-	return nil, errors.New("unsupported data type:" + reflect.TypeOf(dataset[0]).Kind().String())
-}
-
-// pts 必须是指针数组
-func readValueOfPtr(columns []string, fn map[string]string, pts ...interface{}) ([]interface{}, error) {
-	result := make([]interface{}, 0, len(columns)*len(pts))
-	for _, t := range pts {
-		params := make([]interface{}, 0, len(columns))
+	if tp.Kind() == reflect.Ptr {
+		tp = tp.Elem()
+	}
+	if tp.Kind() != reflect.Struct {
+		return nil, errors.New("unsupported data type:" + reflect.TypeOf(dataset[0]).Kind().String())
+	}
+	fields, err := readPlan(metaOf(tp), columns, fn)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]interface{}, 0, len(columns)*len(dataset))
+	for _, t := range dataset {
 		tv := reflect.Indirect(reflect.ValueOf(t))
-		for _, c := range columns {
-			if _, ok := fn[c]; !ok {
-				return nil, errors.New("can not find exported field with tag(" + tag + ") '" + c + "'")
+		if !tv.IsValid() {
+			return nil, errors.New("nil data")
+		}
+		if tv.Type() != tp {
+			return nil, errors.New("dataset must be of the same type, expected " + tp.String() + ", provided type is " + tv.Type().String())
+		}
+		for _, f := range fields {
+			fv, ok := fieldByIndex(tv, f.index)
+			if !ok { // 嵌入的结构体指针为 nil
+				result = append(result, nil)
+				continue
+			}
+			if f.encode == nil {
+				result = append(result, fv.Interface())
+				continue
 			}
-			v := tv.FieldByName(fn[c]).Interface()
-			params = append(params, v)
+			v, err := f.encode(fv)
+			if err != nil {
+				return nil, errors.New("convert field " + f.name + " error:" + err.Error())
+			}
+			result = append(result, v)
 		}
-		result = append(result, params...)
 	}
 	return result, nil
 }
 
-// stucts 必须是结构体数组
-func readValueOfStruct(columns []string, fn map[string]string, structs ...interface{}) ([]interface{}, error) {
-	result := make([]interface{}, 0, len(columns)*len(structs))
-	for _, t := range structs {
-		params := make([]interface{}, 0, len(columns))
-		tv := reflect.ValueOf(t)
-		for _, c := range columns {
-			if _, ok := fn[c]; !ok {
-				return nil, errors.New("can not find exported field with tag(" + tag + ") '" + c + "'")
+// 按 columns 的顺序找到对应的字段
+func readPlan(meta *structMeta, columns []string, fn map[string]string) ([]*fieldMeta, error) {
+	fields := make([]*fieldMeta, 0, len(columns))
+	for _, c := range columns {
+		if f, ok := meta.columns[c]; ok {
+			fields = append(fields, f)
+			continue
+		}
+		if name, ok := fn[c]; ok {
+			if sf, ok := meta.typ.FieldByName(name); ok && sf.PkgPath == "" {
+				fields = append(fields, &fieldMeta{name: sf.Name, column: c, index: sf.Index, typ: sf.Type})
+				continue
 			}
-			v := tv.FieldByName(fn[c]).Interface()
-			params = append(params, v)
 		}
-		result = append(result, params...)
+		return nil, fmt.Errorf("%w: can not find exported field with tag(%s) '%s'", ErrUnknownColumn, tag, c)
 	}
-	return result, nil
+	return fields, nil
 }
 
-// result must be a pointer to slice or struct
-func writeValue(columns []string, p []interface{}, result interface{}) error {
-	m := make(map[string]interface{})
-	for i := 0; i < len(columns); i++ {
-		m[columns[i]] = p[i]
-	}
-	val := reflect.ValueOf(result) // pointer to T
-	ind := reflect.Indirect(val)   // T
-	var v reflect.Value
-	kind := ind.Kind()
-	if reflect.Slice == kind { // 数组
-		if ind.Type().Elem().Kind() == reflect.Ptr { // 指针数组
-			v = reflect.New(ind.Type().Elem().Elem())
-		} else { // 结构体数组
-			v = reflect.New(ind.Type().Elem())
+var strictMapping atomic.Bool // 查询时读取，可能与 StrictMapping 并发
+
+// 严格映射模式，对所有查询生效
+// 开启后查询结果中没有对应字段的 column、不支持的类型转换以及数值溢出都会返回 *MappingError
+// 关闭时这些情况下字段保持零值
+func StrictMapping(strict bool) {
+	strictMapping.Store(strict)
+}
+
+// 将查询结果写入结构体/结构体数组
+type rowMapper struct {
+	kind     reflect.Kind // result 指向的数据的类型
+	elem     reflect.Type // 结构体类型
+	ptr      bool         // 是否为指针数组
+	strict   bool
+	columns  []string
+	fields   []*fieldMeta // 与查询结果的 columns 一一对应，未映射的为 nil
+	snapshot []int        // 嵌入的 Snapshot 的位置
+}
+
+// kind 为 result 指向的数据的类型，elem 为结构体类型
+func newRowMapper(columns []string, kind reflect.Kind, elem reflect.Type, ptr bool, strict bool) (*rowMapper, error) {
+	m := &rowMapper{kind: kind, elem: elem, ptr: ptr, strict: strict, columns: columns}
+	meta := metaOf(m.elem)
+	m.snapshot = meta.snapshot
+	m.fields = make([]*fieldMeta, len(columns))
+	for i, c := range columns {
+		f, ok := meta.columns[c]
+		if !strict {
+			m.fields[i] = f // 没有对应字段的为 nil
+			continue
+		}
+		if !ok {
+			return nil, &MappingError{Column: c, Err: ErrUnmappedColumn}
+		}
+		if f.decode == nil {
+			return nil, &MappingError{Column: c, Field: f.name, FieldType: f.typ, Err: ErrUnsupportedConversion}
 		}
-	} else if reflect.Struct == kind { // 结构体
-		v = reflect.New(ind.Type())
-	} else if reflect.Ptr == kind { // 结构体的指针
-		v = reflect.New(ind.Type().Elem())
-		// 不是结构体数组，也不是结构体
-	} else { // 不是数组，也不是结构体，也不是结构体的指针
-		return errors.New("result is not struct, or pointer to struct, or slice")
+		m.fields[i] = f
 	}
-	_v := reflect.TypeOf(v.Interface())
-	num := _v.Elem().NumField()
-	for index := 0; index < num; index++ {
-		col := strings.TrimSpace(_v.Elem().Field(index).Tag.Get(tag))
-		if col == "" { // this field is not tag-mapping
+	return m, nil
+}
+
+// ind 为 result 指向的数据
+func (a *rowMapper) write(values []interface{}, ind reflect.Value) error {
+	v := reflect.New(a.elem).Elem()
+	for i, f := range a.fields {
+		if f == nil || f.decode == nil { // 未映射的字段或不支持的类型
 			continue
 		}
-		cv, ok := m[col]
-		if !ok { // this field is not selected
+		if values[i] == nil { // 结果是空值
 			continue
 		}
-		if cv == nil { // 结果是空值
-			continue
+		err := f.decode(fieldByIndexAlloc(v, f.index), values[i])
+		if err = mappingError(err, a.strict, a.columns[i], f.name, f.typ, values[i]); err != nil {
+			return err
+		}
+	}
+	if a.snapshot != nil {
+		fieldByIndexAlloc(v, a.snapshot).Set(reflect.ValueOf(takeSnapshot(v, a.fields)))
+	}
+	if reflect.Slice == a.kind {
+		if a.ptr {
+			ind.Set(reflect.Append(ind, v.Addr()))
+		} else {
+			ind.Set(reflect.Append(ind, v))
+		}
+	} else if reflect.Struct == a.kind {
+		ind.Set(v)
+	} else if reflect.Ptr == a.kind {
+		ind.Set(v.Addr())
+	}
+	return nil
+}
+
+// 非严格模式下忽略不支持的类型转换和数值溢出
+func mappingError(err error, strict bool, column, field string, tp reflect.Type, cv interface{}) error {
+	if err == nil {
+		return nil
+	}
+	if !strict && (errors.Is(err, ErrUnsupportedConversion) || errors.Is(err, ErrOverflow)) {
+		return nil
+	}
+	return &MappingError{Column: column, Field: field, FieldType: tp, DriverType: reflect.TypeOf(cv), Err: err}
+}
+
+// 将查询结果 cv 写入字段 field，cv 不为 nil
+type decoder func(field reflect.Value, cv interface{}) error
+
+// 不支持的类型返回 nil
+func decoderOf(tp reflect.Type) decoder {
+	switch tp {
+	case type_time:
+		return decodeTime
+	case type_byte_slice:
+		return decodeBytes
+	}
+	if c, ok := converterOf(tp); ok && c.FromDB != nil {
+		return converterDecoder(tp, c.FromDB)
+	}
+	if reflect.PtrTo(tp).Implements(type_scanner) { // 如 sql.NullString
+		return decodeScanner
+	}
+	switch tp.Kind() {
+	case reflect.Ptr: // 指针字段，查询结果为 NULL 时保持 nil
+		d := decoderOf(tp.Elem())
+		if d == nil {
+			return nil
 		}
-		// fmt.Println(reflect.TypeOf(cv), cv, reflect.TypeOf([]uint8{}))
-		field := v.Elem().Field(index)
-		if field.CanSet() {
-			switch v.Elem().Field(index).Type() {
-			case type_string:
-				if reflect.TypeOf(cv) == type_uint8_slice {
-					_cv := reflect.ValueOf(cv)
-					_b := make([]byte, 0, _cv.Len())
-					for i := 0; i < _cv.Len(); i++ {
-						_b = append(_b, byte(_cv.Index(i).Interface().(uint8)))
-					}
-					field.SetString(string(_b))
-				} else if reflect.TypeOf(cv) == type_byte_slice {
-					_cv := reflect.ValueOf(cv)
-					_b := make([]byte, 0, _cv.Len())
-					for i := 0; i < _cv.Len(); i++ {
-						_b = append(_b, _cv.Index(i).Interface().(byte))
-					}
-					field.SetString(string(_b))
-				} else {
-					return errors.New("only []uint8/[]byte type can be converted to string, provided type is " + reflect.TypeOf(cv).Name())
-				}
-			case type_uint8_slice, type_byte_slice:
-				if reflect.TypeOf(cv) == type_uint8_slice {
-					_cv := reflect.ValueOf(cv)
-					_b := make([]byte, 0, _cv.Len())
-					for i := 0; i < _cv.Len(); i++ {
-						_b = append(_b, byte(_cv.Index(i).Interface().(uint8)))
-					}
-					field.Set(reflect.ValueOf(_b))
-				} else if reflect.TypeOf(cv) == type_byte_slice {
-					_cv := reflect.ValueOf(cv)
-					_b := make([]byte, 0, _cv.Len())
-					for i := 0; i < _cv.Len(); i++ {
-						_b = append(_b, _cv.Index(i).Interface().(byte))
-					}
-					field.Set(reflect.ValueOf(_b))
-				} else {
-					return errors.New("only []uint8/[]byte type can be converted to []uint8, provided type is " + reflect.TypeOf(cv).Name())
-				}
-			case type_int, type_int8, type_int16, type_int32, type_int64: // 字段定义的精度和实际的精度不一定相符合
-				if val_int, ok := cv.(int); ok {
-					field.SetInt(int64(val_int))
-				} else if val_8, ok := cv.(int8); ok {
-					field.SetInt(int64(val_8))
-				} else if val_16, ok := cv.(int16); ok {
-					field.SetInt(int64(val_16))
-				} else if val_32, ok := cv.(int32); ok {
-					field.SetInt(int64(val_32))
-				} else if val_64, ok := cv.(int64); ok {
-					field.SetInt(val_64)
-				} else if val_u, ok := cv.(uint); ok {
-					field.SetInt(int64(val_u))
-				} else if val_u8, ok := cv.(uint8); ok {
-					field.SetInt(int64(val_u8))
-				} else if val_u16, ok := cv.(uint16); ok {
-					field.SetInt(int64(val_u16))
-				} else if val_u32, ok := cv.(uint32); ok {
-					field.SetInt(int64(val_u32))
-				} else if val_u64, ok := cv.(uint64); ok {
-					field.SetInt(int64(val_u64))
-				} else if val_uint8_slice, ok := cv.([]uint8); ok {
-					_cv := reflect.ValueOf(val_uint8_slice)
-					_b := make([]byte, 0, _cv.Len())
-					for i := 0; i < _cv.Len(); i++ {
-						_b = append(_b, byte(_cv.Index(i).Interface().(uint8)))
-					}
-					val_64, err := strconv.ParseInt(string(_b), 10, 64)
-					if err != nil {
-						return errors.New("convert value from []uint8 to " + v.Elem().Field(index).Type().Name() + " error:" + err.Error())
-					}
-					field.SetInt(val_64)
-				} else if val_byte_slice, ok := cv.([]byte); ok {
-					_cv := reflect.ValueOf(val_byte_slice)
-					_b := make([]byte, 0, _cv.Len())
-					for i := 0; i < _cv.Len(); i++ {
-						_b = append(_b, _cv.Index(i).Interface().(byte))
-					}
-					val_64, err := strconv.ParseInt(string(_b), 10, 64)
-					if err != nil {
-						return errors.New("convert value from []byte to " + v.Elem().Field(index).Type().Name() + " error:" + err.Error())
-					}
-					field.SetInt(val_64)
-				}
-			case type_float32, type_float64: // 字段定义的精度和实际的精度不一定相符合
-				if _val32, ok := cv.(float32); ok {
-					field.SetFloat(float64(_val32))
-				} else if _val64, ok := cv.(float64); ok {
-					field.SetFloat(float64(_val64))
-				} else if val_uint8_slice, ok := cv.([]uint8); ok {
-					_cv := reflect.ValueOf(val_uint8_slice)
-					_b := make([]byte, 0, _cv.Len())
-					for i := 0; i < _cv.Len(); i++ {
-						_b = append(_b, byte(_cv.Index(i).Interface().(uint8)))
-					}
-					fv, err := strconv.ParseFloat(string(_b), 64)
-					if err != nil {
-						return errors.New("convert value from []uint8 to " + v.Elem().Field(index).Type().Name() + " error:" + err.Error())
-					}
-					field.SetFloat(fv)
-				} else if val_byte_slice, ok := cv.([]byte); ok {
-					_cv := reflect.ValueOf(val_byte_slice)
-					_b := make([]byte, 0, _cv.Len())
-					for i := 0; i < _cv.Len(); i++ {
-						_b = append(_b, _cv.Index(i).Interface().(byte))
-					}
-					fv, err := strconv.ParseFloat(string(_b), 64)
-					if err != nil {
-						return errors.New("convert value from []byte to " + v.Elem().Field(index).Type().Name() + " error:" + err.Error())
-					}
-					field.SetFloat(fv)
-				}
-			case type_uint, type_uint8, type_uint16, type_uint32, type_uint64: // 字段定义的精度和实际的精度不一定相符合
-				if val_int, ok := cv.(int); ok {
-					field.SetUint(uint64(val_int))
-				} else if val_8, ok := cv.(int8); ok {
-					field.SetUint(uint64(val_8))
-				} else if val_16, ok := cv.(int16); ok {
-					field.SetUint(uint64(val_16))
-				} else if val_32, ok := cv.(int32); ok {
-					field.SetUint(uint64(val_32))
-				} else if val_64, ok := cv.(int64); ok {
-					field.SetUint(uint64(val_64))
-				} else if val_u, ok := cv.(uint); ok {
-					field.SetUint(uint64(val_u))
-				} else if val_u8, ok := cv.(uint8); ok {
-					field.SetUint(uint64(val_u8))
-				} else if val_u16, ok := cv.(uint16); ok {
-					field.SetUint(uint64(val_u16))
-				} else if val_u32, ok := cv.(uint32); ok {
-					field.SetUint(uint64(val_u32))
-				} else if val_u64, ok := cv.(uint64); ok {
-					field.SetUint(uint64(val_u64))
-				} else if val_uint8_slice, ok := cv.([]uint8); ok {
-					_cv := reflect.ValueOf(val_uint8_slice)
-					_b := make([]byte, 0, _cv.Len())
-					for i := 0; i < _cv.Len(); i++ {
-						_b = append(_b, byte(_cv.Index(i).Interface().(uint8)))
-					}
-					val_u64, err := strconv.ParseUint(string(_b), 10, 64)
-					if err != nil {
-						return errors.New("convert value from []uint8 to " + v.Elem().Field(index).Type().Name() + " error:" + err.Error())
-					}
-					field.SetUint(val_u64)
-				} else if val_byte_slice, ok := cv.([]byte); ok {
-					_cv := reflect.ValueOf(val_byte_slice)
-					_b := make([]byte, 0, _cv.Len())
-					for i := 0; i < _cv.Len(); i++ {
-						_b = append(_b, _cv.Index(i).Interface().(byte))
-					}
-					val_u64, err := strconv.ParseUint(string(_b), 10, 64)
-					if err != nil {
-						return errors.New("convert value from []byte to " + v.Elem().Field(index).Type().Name() + " error:" + err.Error())
-					}
-					field.SetUint(val_u64)
-				}
-			case type_time:
-				_cv := reflect.ValueOf(cv)
-				field.Set(_cv)
+		return func(field reflect.Value, cv interface{}) error {
+			v := reflect.New(tp.Elem())
+			if err := d(v.Elem(), cv); err != nil {
+				return err
 			}
+			field.Set(v)
+			return nil
 		}
+	case reflect.Bool:
+		return decodeBool
+	case reflect.String:
+		return decodeString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64: // 字段定义的精度和实际的精度不一定相符合
+		return decodeInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return decodeUint
+	case reflect.Float32, reflect.Float64:
+		return decodeFloat
 	}
-	if reflect.Slice == kind {
-		if ind.Type().Elem().Kind() == reflect.Ptr {
-			val = reflect.Append(reflect.Indirect(val), v.Elem().Addr())
-			ind.Set(val)
-		} else {
-			val = reflect.Append(reflect.Indirect(val), v.Elem())
-			ind.Set(val)
+	return nil
+}
+
+func decodeString(field reflect.Value, cv interface{}) error {
+	switch v := cv.(type) {
+	case []byte:
+		field.SetString(string(v))
+	case string:
+		field.SetString(v)
+	default:
+		return ErrUnsupportedConversion
+	}
+	return nil
+}
+
+func decodeBytes(field reflect.Value, cv interface{}) error {
+	switch v := cv.(type) {
+	case []byte:
+		field.SetBytes(append([]byte(nil), v...))
+	case string:
+		field.SetBytes([]byte(v))
+	default:
+		return ErrUnsupportedConversion
+	}
+	return nil
+}
+
+// 字段定义的精度和实际的精度不一定相符合，超出字段范围时返回 ErrOverflow
+func decodeInt(field reflect.Value, cv interface{}) error {
+	var i int64
+	switch v := cv.(type) {
+	case int:
+		i = int64(v)
+	case int8:
+		i = int64(v)
+	case int16:
+		i = int64(v)
+	case int32:
+		i = int64(v)
+	case int64:
+		i = v
+	case uint:
+		if uint64(v) > math.MaxInt64 {
+			return ErrOverflow
+		}
+		i = int64(v)
+	case uint8:
+		i = int64(v)
+	case uint16:
+		i = int64(v)
+	case uint32:
+		i = int64(v)
+	case uint64:
+		if v > math.MaxInt64 {
+			return ErrOverflow
+		}
+		i = int64(v)
+	case []byte:
+		var err error
+		i, err = strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return errors.New("convert value from []uint8 to " + field.Type().Name() + " error:" + err.Error())
 		}
-	} else if reflect.Struct == kind {
-		ind.Set(v.Elem())
-	} else if reflect.Ptr == kind {
-		ind.Set(v.Elem().Addr())
+	default:
+		return ErrUnsupportedConversion
 	}
+	if field.OverflowInt(i) {
+		return ErrOverflow
+	}
+	field.SetInt(i)
 	return nil
 }
 
-var (
-	const_string      string    = ""
-	const_int         int       = math.MaxInt
-	const_int8        int8      = math.MaxInt8
-	const_int16       int16     = math.MaxInt16
-	const_int32       int32     = math.MaxInt32
-	const_int64       int64     = math.MaxInt64
-	const_float32     float32   = math.MaxFloat32
-	const_float64     float64   = math.MaxFloat64
-	const_uint        uint      = math.MaxUint
-	const_uint8       uint8     = math.MaxUint8
-	const_uint16      uint16    = math.MaxUint16
-	const_uint32      uint32    = math.MaxUint32
-	const_uint64      uint64    = math.MaxUint64
-	const_time        time.Time = time.Now()
-	const_uint8_slice []uint8   = []uint8{}
-	const_byte_slice  []byte    = []byte{}
-)
+func decodeUint(field reflect.Value, cv interface{}) error {
+	var u uint64
+	var i int64
+	signed := true
+	switch v := cv.(type) {
+	case int:
+		i = int64(v)
+	case int8:
+		i = int64(v)
+	case int16:
+		i = int64(v)
+	case int32:
+		i = int64(v)
+	case int64:
+		i = v
+	case uint:
+		u, signed = uint64(v), false
+	case uint8:
+		u, signed = uint64(v), false
+	case uint16:
+		u, signed = uint64(v), false
+	case uint32:
+		u, signed = uint64(v), false
+	case uint64:
+		u, signed = v, false
+	case []byte:
+		var err error
+		u, err = strconv.ParseUint(string(v), 10, 64)
+		if err != nil {
+			return errors.New("convert value from []uint8 to " + field.Type().Name() + " error:" + err.Error())
+		}
+		signed = false
+	default:
+		return ErrUnsupportedConversion
+	}
+	if signed {
+		if i < 0 {
+			return ErrOverflow
+		}
+		u = uint64(i)
+	}
+	if field.OverflowUint(u) {
+		return ErrOverflow
+	}
+	field.SetUint(u)
+	return nil
+}
+
+func decodeFloat(field reflect.Value, cv interface{}) error {
+	var f float64
+	switch v := cv.(type) {
+	case float32:
+		f = float64(v)
+	case float64:
+		f = v
+	case int64:
+		f = float64(v)
+	case []byte:
+		var err error
+		f, err = strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return errors.New("convert value from []uint8 to " + field.Type().Name() + " error:" + err.Error())
+		}
+	default:
+		return ErrUnsupportedConversion
+	}
+	if field.OverflowFloat(f) {
+		return ErrOverflow
+	}
+	field.SetFloat(f)
+	return nil
+}
+
+func decodeBool(field reflect.Value, cv interface{}) error {
+	switch v := cv.(type) {
+	case bool:
+		field.SetBool(v)
+	case int64: // 如 MySQL 的 tinyint(1)
+		field.SetBool(v != 0)
+	case []byte:
+		b, err := strconv.ParseBool(string(v))
+		if err != nil {
+			return errors.New("convert value from []uint8 to " + field.Type().Name() + " error:" + err.Error())
+		}
+		field.SetBool(b)
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return errors.New("convert value from string to " + field.Type().Name() + " error:" + err.Error())
+		}
+		field.SetBool(b)
+	default:
+		return ErrUnsupportedConversion
+	}
+	return nil
+}
+
+func decodeTime(field reflect.Value, cv interface{}) error {
+	t, ok := cv.(time.Time)
+	if !ok {
+		return ErrUnsupportedConversion
+	}
+	field.Set(reflect.ValueOf(t))
+	return nil
+}
 
 var (
-	type_string      = reflect.TypeOf(const_string)
-	type_int         = reflect.TypeOf(const_int)
-	type_int8        = reflect.TypeOf(const_int8)
-	type_int16       = reflect.TypeOf(const_int16)
-	type_int32       = reflect.TypeOf(const_int32)
-	type_int64       = reflect.TypeOf(const_int64)
-	type_float32     = reflect.TypeOf(const_float32)
-	type_float64     = reflect.TypeOf(const_float64)
-	type_uint        = reflect.TypeOf(const_uint)
-	type_uint8       = reflect.TypeOf(const_uint8)
-	type_uint16      = reflect.TypeOf(const_uint16)
-	type_uint32      = reflect.TypeOf(const_uint32)
-	type_uint64      = reflect.TypeOf(const_uint64)
-	type_time        = reflect.TypeOf(const_time)
-	type_uint8_slice = reflect.TypeOf(const_uint8_slice)
-	type_byte_slice  = reflect.TypeOf(const_byte_slice)
+	type_time       = reflect.TypeOf(time.Time{})
+	type_byte_slice = reflect.TypeOf([]byte{})
 )
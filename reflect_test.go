@@ -0,0 +1,212 @@
+package orm
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecoders(t *testing.T) {
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	cases := []struct {
+		name string
+		dst  interface{} // 字段的指针
+		cv   interface{}
+		want interface{}
+		err  error
+	}{
+		{"string from bytes", new(string), []byte("abc"), "abc", nil},
+		{"string from string", new(string), "abc", "abc", nil},
+		{"string from int", new(string), int64(1), nil, ErrUnsupportedConversion},
+		{"bytes from bytes", new([]byte), []byte("abc"), []byte("abc"), nil},
+		{"bytes from string", new([]byte), "abc", []byte("abc"), nil},
+		{"int from int64", new(int), int64(-5), -5, nil},
+		{"int from bytes", new(int64), []byte("42"), int64(42), nil},
+		{"int8 overflow", new(int8), int64(300), nil, ErrOverflow},
+		{"int from large uint64", new(int64), uint64(math.MaxUint64), nil, ErrOverflow},
+		{"int from float", new(int), 1.5, nil, ErrUnsupportedConversion},
+		{"uint from int64", new(uint32), int64(7), uint32(7), nil},
+		{"uint from negative", new(uint), int64(-1), nil, ErrOverflow},
+		{"uint8 overflow", new(uint8), uint64(256), nil, ErrOverflow},
+		{"uint from bytes", new(uint64), []byte("18446744073709551615"), uint64(math.MaxUint64), nil},
+		{"float from float64", new(float64), 1.25, 1.25, nil},
+		{"float from bytes", new(float64), []byte("2.5"), 2.5, nil},
+		{"float from int64", new(float32), int64(3), float32(3), nil},
+		{"float32 overflow", new(float32), math.MaxFloat64, nil, ErrOverflow},
+		{"bool from int64", new(bool), int64(1), true, nil},
+		{"bool from bytes", new(bool), []byte("true"), true, nil},
+		{"bool from string", new(bool), "0", false, nil},
+		{"time from time", new(time.Time), now, now, nil},
+		{"time from string", new(time.Time), "2024-05-06", nil, ErrUnsupportedConversion},
+		{"pointer from int64", new(*int), int64(3), 3, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			field := reflect.ValueOf(c.dst).Elem()
+			d := decoderOf(field.Type())
+			if d == nil {
+				t.Fatalf("no decoder for %s", field.Type())
+			}
+			err := d(field, c.cv)
+			if c.err != nil {
+				if !errors.Is(err, c.err) {
+					t.Fatalf("err = %v, want %v", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := reflect.Indirect(field).Interface()
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %v (%T), want %v (%T)", got, got, c.want, c.want)
+			}
+		})
+	}
+}
+
+func TestDecodeIntParseError(t *testing.T) {
+	var i int
+	if err := decodeInt(reflect.ValueOf(&i).Elem(), []byte("x")); err == nil {
+		t.Fatal("want parse error")
+	}
+}
+
+func TestDecodeBytesCopies(t *testing.T) {
+	src := []byte("abc")
+	var b []byte
+	if err := decodeBytes(reflect.ValueOf(&b).Elem(), src); err != nil {
+		t.Fatal(err)
+	}
+	src[0] = 'z'
+	if string(b) != "abc" {
+		t.Fatalf("b = %s, want abc", b)
+	}
+}
+
+type nullPerson struct {
+	ID       int64          `column:"id,pk"`
+	Name     sql.NullString `column:"name"`
+	Age      *int           `column:"age"`
+	Nick     sql.NullString `column:"nick"`
+	Birthday *time.Time     `column:"birthday"`
+}
+
+func (nullPerson) TableName() string { return "person" }
+
+func TestNullableFields(t *testing.T) {
+	c := openTestContext(t)
+	age := 3
+	birthday := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	ps := []nullPerson{{Name: sql.NullString{String: "a", Valid: true}, Age: &age, Birthday: &birthday}, {}}
+	if _, err := c.InsertModel(ps).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	var rs []nullPerson
+	if err := c.Select("person", ColumnsExcept(nullPerson{}), "").OrderByAsc("id").Result(&rs); err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 {
+		t.Fatalf("rows = %+v", rs)
+	}
+	a, b := rs[0], rs[1]
+	if !a.Name.Valid || a.Name.String != "a" || a.Age == nil || *a.Age != 3 || a.Nick.Valid || a.Birthday == nil || !a.Birthday.Equal(birthday) {
+		t.Errorf("first row = %+v", a)
+	}
+	if b.Name.Valid || b.Age != nil || b.Birthday != nil {
+		t.Errorf("second row = %+v", b)
+	}
+}
+
+func TestBoolField(t *testing.T) {
+	c := openTestContext(t)
+	var flags []struct {
+		T bool `column:"t"`
+		F bool `column:"f"`
+	}
+	if err := c.Search("select 1 as t, 0 as f").Result(&flags); err != nil {
+		t.Fatal(err)
+	}
+	if len(flags) != 1 || !flags[0].T || flags[0].F {
+		t.Fatalf("flags = %+v", flags)
+	}
+}
+
+type strictPerson struct {
+	ID   int8 `column:"id"`
+	Name int  `column:"name"`
+}
+
+func TestStrictMapping(t *testing.T) {
+	c := openTestContext(t)
+	if _, err := c.Insert("person", []string{"id", "name"}, testPerson{ID: 1000, Name: "x"}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	// 非严格模式下字段保持零值
+	var rs []strictPerson
+	if err := c.Select("person", []string{"id", "name", "age"}, "").Result(&rs); err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || rs[0].ID != 0 || rs[0].Name != 0 {
+		t.Fatalf("rows = %+v", rs)
+	}
+	cases := []struct {
+		columns []string
+		column  string
+		err     error
+	}{
+		{[]string{"id", "age"}, "age", ErrUnmappedColumn},
+		{[]string{"id"}, "id", ErrOverflow},
+		{[]string{"name"}, "name", ErrUnsupportedConversion},
+	}
+	for _, cs := range cases {
+		err := c.Select("person", cs.columns, "").Strict().Result(&rs)
+		var me *MappingError
+		if !errors.As(err, &me) || !errors.Is(err, cs.err) || me.Column != cs.column {
+			t.Errorf("%v: err = %v, want %v on column %s", cs.columns, err, cs.err, cs.column)
+		}
+	}
+	StrictMapping(true)
+	defer StrictMapping(false)
+	if err := c.Select("person", []string{"id"}, "").Result(&rs); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("global strict: err = %v, want ErrOverflow", err)
+	}
+}
+
+// 需要通过 go test -race 运行
+func TestStrictMappingConcurrency(t *testing.T) {
+	c := openTestContext(t)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			var ids []int64
+			if err := c.Select("person", []string{"id"}, "").Result(&ids); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		StrictMapping(i%2 == 0)
+	}
+	StrictMapping(false)
+	<-done
+}
+
+func TestScanError(t *testing.T) {
+	c := openTestContext(t)
+	if _, err := c.Insert("person", []string{"name"}, testPerson{Name: "x"}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	var rs []testPerson
+	if err := c.Search("select id, name from missing").Result(&rs); err == nil {
+		t.Fatal("want error for missing table")
+	}
+	if err := c.Search("select name as age from person").Strict().Result(&rs); !errors.Is(err, ErrUnsupportedConversion) {
+		t.Fatalf("err = %v, want ErrUnsupportedConversion", err)
+	}
+}
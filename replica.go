@@ -0,0 +1,140 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultHealthCheckInterval = 10 * time.Second
+
+const defaultDrainTimeout = time.Minute
+
+type replicaConfig struct {
+	dns    string
+	weight int
+}
+
+type replica struct {
+	db      *sql.DB
+	healthy int32 // 1 健康 0 不健康
+}
+
+func (a *replica) isHealthy() bool {
+	return atomic.LoadInt32(&a.healthy) == 1
+}
+
+func (a *replica) check(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if a.db.PingContext(ctx) == nil {
+		atomic.StoreInt32(&a.healthy, 1)
+	} else {
+		atomic.StoreInt32(&a.healthy, 0)
+	}
+}
+
+// 从库初始为不健康，由健康检查连接成功后开始使用，避免不可用的从库阻塞注册
+func (a *datasource) addReplica(db *sql.DB, weight int) {
+	r := &replica{db: db}
+	a.replicas = append(a.replicas, r)
+	for i := 0; i < weight; i++ {
+		a.slots = append(a.slots, len(a.replicas)-1)
+	}
+}
+
+// 按权重轮询选择健康的从库，没有可用从库时返回主库
+func (a *datasource) replica() *sql.DB {
+	if len(a.slots) == 0 {
+		return a.db
+	}
+	n := a.counter.Add(1)
+	for i := 0; i < len(a.slots); i++ {
+		r := a.replicas[a.slots[(n+uint64(i))%uint64(len(a.slots))]]
+		if r.isHealthy() {
+			return r.db
+		}
+	}
+	return a.db
+}
+
+// 启动后立即检查一次，之后每隔 interval 检查一次，timeout 为每次 ping 的超时时间，为 0 时使用 interval
+func (a *datasource) startHealthCheck(interval, timeout time.Duration) {
+	if len(a.replicas) == 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	if timeout <= 0 || timeout > interval {
+		timeout = interval
+	}
+	a.stop = make(chan struct{})
+	go func(stop chan struct{}) {
+		a.checkReplicas(timeout)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				a.checkReplicas(timeout)
+			}
+		}
+	}(a.stop)
+}
+
+// 并发检查所有从库，不可用的从库不会延迟其他从库的检查
+func (a *datasource) checkReplicas(timeout time.Duration) {
+	var wg sync.WaitGroup
+	for _, r := range a.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+			r.check(timeout)
+		}(r)
+	}
+	wg.Wait()
+}
+
+func (a *datasource) stopHealthCheck() {
+	if a.stop != nil {
+		close(a.stop)
+		a.stop = nil
+	}
+}
+
+// 停止健康检查并关闭主库和所有从库
+func (a *datasource) close() error {
+	return a.release(true)
+}
+
+// 被替换的数据源在 drain 后关闭，期间此前创建的 Context 仍然可以使用
+// primary 为新数据源的主库，与原有主库相同时只释放从库
+func (a *datasource) retire(primary *sql.DB, drain time.Duration) error {
+	shared := a.db == primary
+	if drain <= 0 {
+		return a.release(!shared)
+	}
+	time.AfterFunc(drain, func() {
+		a.release(!shared)
+	})
+	return nil
+}
+
+func (a *datasource) release(primary bool) error {
+	a.stopHealthCheck()
+	var err error
+	if primary {
+		err = a.db.Close()
+	}
+	for _, r := range a.replicas {
+		if e := r.db.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
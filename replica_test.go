@@ -0,0 +1,108 @@
+package orm
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// 包含 marker 表的内存数据库，用于区分查询在哪个库上执行
+func openMarkerDB(t *testing.T, marker string) *sql.DB {
+	t.Helper()
+	db := openTestDB(t)
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("create table marker (v text)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("insert into marker values (?)", marker); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestReplicaRouting(t *testing.T) {
+	primary, r1, r2 := openTestDB(t), openTestDB(t), openTestDB(t)
+	source := &datasource{db: primary, dialect: SQLite}
+	defer source.close()
+	if source.replica() != primary {
+		t.Fatal("want primary without replicas")
+	}
+	source.addReplica(r1, 2)
+	source.addReplica(r2, 1)
+	if source.replica() != primary {
+		t.Fatal("replicas must start unhealthy")
+	}
+	source.replicas[0].healthy, source.replicas[1].healthy = 1, 1
+	count := map[*sql.DB]int{}
+	for i := 0; i < 300; i++ {
+		count[source.replica()]++
+	}
+	if count[r1] != 200 || count[r2] != 100 {
+		t.Fatalf("r1 = %d, r2 = %d, want 200, 100", count[r1], count[r2])
+	}
+	source.replicas[0].healthy = 0
+	for i := 0; i < 10; i++ {
+		if source.replica() != r2 {
+			t.Fatal("unhealthy replica is selected")
+		}
+	}
+	source.replicas[1].healthy = 0
+	if source.replica() != primary {
+		t.Fatal("want primary when no replica is healthy")
+	}
+}
+
+func TestReplicaHealthCheck(t *testing.T) {
+	primary, r := openTestDB(t), openTestDB(t)
+	source := &datasource{db: primary, dialect: SQLite}
+	source.addReplica(r, 1)
+	source.startHealthCheck(10*time.Millisecond, 0)
+	defer source.close()
+	waitHealthy := func(want bool) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for source.replicas[0].isHealthy() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("healthy = %v, want %v", !want, want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitHealthy(true)
+	r.Close()
+	waitHealthy(false)
+	if source.replica() != primary {
+		t.Fatal("want primary when the replica is down")
+	}
+}
+
+func TestForcePrimary(t *testing.T) {
+	name := t.Name()
+	source := &datasource{db: openMarkerDB(t, "primary"), dialect: SQLite}
+	source.addReplica(openMarkerDB(t, "replica"), 1)
+	source.replicas[0].healthy = 1
+	if err := register(name, source); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterDatasource(name)
+	c, _ := CreateContextOf(name)
+	var v string
+	if err := c.Select("marker", []string{"v"}, "").Result(&v); err != nil || v != "replica" {
+		t.Fatalf("Select = %s, %v, want replica", v, err)
+	}
+	if err := c.Search("select v from marker").Result(&v); err != nil || v != "replica" {
+		t.Fatalf("Search = %s, %v, want replica", v, err)
+	}
+	if err := c.Select("marker", []string{"v"}, "").ForcePrimary().Result(&v); err != nil || v != "primary" {
+		t.Fatalf("ForcePrimary = %s, %v, want primary", v, err)
+	}
+	// 事务中的查询总是在主库上执行
+	tx, err := c.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := tx.Select("marker", []string{"v"}, "").Result(&v); err != nil || v != "primary" {
+		t.Fatalf("tx Select = %s, %v, want primary", v, err)
+	}
+}
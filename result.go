@@ -0,0 +1,119 @@
+package orm
+
+import (
+	"reflect"
+)
+
+// 将一行查询结果写入 result 指向的数据 ind
+type rowWriter interface {
+	write(values []interface{}, ind reflect.Value) error
+}
+
+var type_map = reflect.TypeOf(map[string]interface{}{})
+
+// result 必须是指针，支持指向以下类型
+// 1. struct, *struct 及它们的数组
+// 2. map[string]interface{} 及其数组
+// 3. int64, string, time.Time 等基本类型(包括实现了 sql.Scanner 的类型)及其数组，取查询结果的第一列
+func newRowWriter(columns []string, result interface{}, strict bool) (rowWriter, bool, error) {
+	val := reflect.ValueOf(result)
+	if !val.IsValid() || val.Kind() != reflect.Ptr || val.IsNil() {
+		return nil, false, new(InvalidResultTypeError)
+	}
+	tp := val.Type().Elem()
+	slice := tp.Kind() == reflect.Slice && tp != type_byte_slice
+	elem := tp
+	if slice {
+		elem = tp.Elem()
+	}
+	switch {
+	case isModel(elem):
+		return mapperOf(columns, tp.Kind(), elem, false, strict, slice)
+	case elem.Kind() == reflect.Ptr && isModel(elem.Elem()):
+		if slice {
+			return mapperOf(columns, reflect.Slice, elem.Elem(), true, strict, slice)
+		}
+		return mapperOf(columns, reflect.Ptr, elem.Elem(), false, strict, slice)
+	case elem == type_map:
+		return &mapMapper{slice: slice, columns: columns}, slice, nil
+	}
+	decode := decoderOf(elem)
+	if decode == nil {
+		return nil, false, new(InvalidResultTypeError)
+	}
+	if strict && len(columns) > 1 {
+		return nil, false, &MappingError{Column: columns[1], Err: ErrUnmappedColumn}
+	}
+	m := &scalarMapper{slice: slice, typ: elem, decode: decode, strict: strict}
+	if len(columns) > 0 {
+		m.column = columns[0]
+	}
+	return m, slice, nil
+}
+
+// 执行查询前检查 result 的类型
+func checkResult(result interface{}) error {
+	_, _, err := newRowWriter(nil, result, false)
+	return err
+}
+
+func mapperOf(columns []string, kind reflect.Kind, elem reflect.Type, ptr, strict, slice bool) (rowWriter, bool, error) {
+	m, err := newRowMapper(columns, kind, elem, ptr, strict)
+	if err != nil {
+		return nil, false, err
+	}
+	return m, slice, nil
+}
+
+// 需要按字段映射的结构体，time.Time 等有对应转换方式的结构体除外
+func isModel(tp reflect.Type) bool {
+	return tp.Kind() == reflect.Struct && decoderOf(tp) == nil
+}
+
+// 将第一列写入基本类型
+type scalarMapper struct {
+	slice  bool
+	typ    reflect.Type
+	decode decoder
+	strict bool
+	column string
+}
+
+func (a *scalarMapper) write(values []interface{}, ind reflect.Value) error {
+	v := reflect.New(a.typ).Elem()
+	if values[0] != nil {
+		err := a.decode(v, values[0])
+		if err = mappingError(err, a.strict, a.column, "", a.typ, values[0]); err != nil {
+			return err
+		}
+	}
+	if a.slice {
+		ind.Set(reflect.Append(ind, v))
+	} else {
+		ind.Set(v)
+	}
+	return nil
+}
+
+// 将一行写入 map[string]interface{}，[]byte 会转为 string
+type mapMapper struct {
+	slice   bool
+	columns []string
+}
+
+func (a *mapMapper) write(values []interface{}, ind reflect.Value) error {
+	m := make(map[string]interface{}, len(values))
+	for i, c := range a.columns {
+		if b, ok := values[i].([]byte); ok {
+			m[c] = string(b)
+		} else {
+			m[c] = values[i]
+		}
+	}
+	if a.slice {
+		ind.Set(reflect.Append(ind, reflect.ValueOf(m)))
+	} else {
+		ind.Set(reflect.ValueOf(m))
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package orm
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+)
+
+// Each 的回调返回 ErrStop 时停止遍历，Each 返回 nil
+var ErrStop = errors.New("stop iteration")
+
+// 逐行读取的查询结果，用法与 sql.Rows 相同
+//
+//	rows, err := ctx.Select("person", cols, "").Rows()
+//	if err != nil {
+//		return err
+//	}
+//	defer rows.Close()
+//	for rows.Next() {
+//		var p Person
+//		if err := rows.Scan(&p); err != nil {
+//			return err
+//		}
+//	}
+//	return rows.Err()
+type Rows struct {
+	stat    *sql.Stmt
+	rows    *sql.Rows
+	columns []string
+	values  []interface{}
+	dest    []interface{}
+	strict  bool
+	// 上一次 Scan 的类型及其映射
+	tp     reflect.Type
+	writer rowWriter
+	slice  bool
+}
+
+func newRows(stat *sql.Stmt, rows *sql.Rows, columns []string, strict bool) *Rows {
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	return &Rows{stat: stat, rows: rows, columns: columns, values: values, dest: dest, strict: strict}
+}
+
+func (a *Rows) Columns() []string {
+	return a.columns
+}
+
+func (a *Rows) Next() bool {
+	return a.rows.Next()
+}
+
+// 将当前行写入 r，r 支持的类型与 SelectContext.Result 相同，r 为数组时追加到数组中
+func (a *Rows) Scan(r interface{}) error {
+	writer, _, err := a.writerOf(r)
+	if err != nil {
+		return err
+	}
+	if err := a.rows.Scan(a.dest...); err != nil {
+		return wrapDBError(err)
+	}
+	return writer.write(a.values, reflect.ValueOf(r).Elem())
+}
+
+func (a *Rows) writerOf(r interface{}) (rowWriter, bool, error) {
+	tp := reflect.TypeOf(r)
+	if tp != nil && tp == a.tp {
+		return a.writer, a.slice, nil
+	}
+	writer, slice, err := newRowWriter(a.columns, r, a.strict)
+	if err != nil {
+		return nil, false, err
+	}
+	a.tp, a.writer, a.slice = tp, writer, slice
+	return writer, slice, nil
+}
+
+func (a *Rows) Err() error {
+	return wrapDBError(a.rows.Err())
+}
+
+func (a *Rows) Close() error {
+	err := a.rows.Close()
+	a.stat.Close()
+	return err
+}
+
+var type_error = reflect.TypeOf((*error)(nil)).Elem()
+
+// 逐行处理查询结果，不会将所有结果读入内存
+// fn 的类型为 func(*T) error 或 func(T) error，T 可以是 Result 支持的除数组外的类型
+// fn 返回 ErrStop 时停止遍历并返回 nil，返回其他异常时停止遍历并返回该异常
+//
+//	err := ctx.Select("person", cols, "").Each(func(p *Person) error {
+//		return writer.Write(p)
+//	})
+func (a *SelectContext) Each(fn interface{}) error {
+	if a.err != nil { // 如果构建异常，不执行
+		return a.err
+	}
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func || fv.Type().NumIn() != 1 || fv.Type().NumOut() != 1 || fv.Type().Out(0) != type_error {
+		return errors.New("fn must be func(*T) error or func(T) error")
+	}
+	arg := fv.Type().In(0)
+	elem := arg
+	if arg.Kind() == reflect.Ptr {
+		elem = arg.Elem()
+	}
+	if elem.Kind() == reflect.Slice && elem != type_byte_slice {
+		return new(InvalidResultTypeError)
+	}
+	if err := checkResult(reflect.New(elem).Interface()); err != nil {
+		return err
+	}
+	rows, err := a.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		p := reflect.New(elem)
+		if err := rows.Scan(p.Interface()); err != nil {
+			return err
+		}
+		in := p
+		if arg.Kind() != reflect.Ptr {
+			in = p.Elem()
+		}
+		if err, _ := fv.Call([]reflect.Value{in})[0].Interface().(error); err != nil {
+			if err == ErrStop {
+				return nil
+			}
+			return err
+		}
+	}
+	return rows.Err()
+}
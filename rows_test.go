@@ -0,0 +1,86 @@
+package orm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRows(t *testing.T) {
+	c := openTestContext(t)
+	insertNames(t, c, "a", "b", "c")
+	rows, err := c.Select("person", []string{"id", "name", "age"}, "").OrderByAsc("id").Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if cols := rows.Columns(); len(cols) != 3 || cols[1] != "name" {
+		t.Fatalf("columns = %v", cols)
+	}
+	var ps []*testPerson
+	for rows.Next() {
+		var p testPerson
+		if err := rows.Scan(&p); err != nil {
+			t.Fatal(err)
+		}
+		if err := rows.Scan(&ps); err != nil { // 数组时追加
+			t.Fatal(err)
+		}
+		if p.Name != ps[len(ps)-1].Name {
+			t.Fatalf("%+v != %+v", p, ps[len(ps)-1])
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 3 || ps[2].Name != "c" || ps[2].Age != 3 {
+		t.Fatalf("rows = %+v", ps)
+	}
+	if _, err := c.Select("person", []string{"nope"}, "").Rows(); !errors.Is(err, ErrUnknownColumn) {
+		t.Fatalf("err = %v, want ErrUnknownColumn", err)
+	}
+}
+
+func TestEach(t *testing.T) {
+	c := openTestContext(t)
+	insertNames(t, c, "a", "b", "c", "d")
+	var names []string
+	err := c.Select("person", []string{"id", "name"}, "").OrderByAsc("id").Each(func(p *testPerson) error {
+		names = append(names, p.Name)
+		return nil
+	})
+	if err != nil || len(names) != 4 || names[3] != "d" {
+		t.Fatalf("names = %v, %v", names, err)
+	}
+	// 返回 ErrStop 时停止，Each 返回 nil
+	count := 0
+	err = c.Select("person", []string{"id", "name"}, "").Each(func(p testPerson) error {
+		count++
+		if count == 2 {
+			return ErrStop
+		}
+		return nil
+	})
+	if err != nil || count != 2 {
+		t.Fatalf("count = %d, %v", count, err)
+	}
+	sum := 0
+	if err := c.Select("person", []string{"age"}, "").Each(func(age int) error { sum += age; return nil }); err != nil || sum != 10 {
+		t.Fatalf("sum = %d, %v", sum, err)
+	}
+	fail := errors.New("fail")
+	if err := c.Select("person", nil, "").Each(func(p testPerson) error { return fail }); err != fail {
+		t.Fatalf("err = %v, want the callback error", err)
+	}
+	invalid := []interface{}{
+		nil,
+		func(p testPerson) {},
+		func(p testPerson) bool { return true },
+		func(ps []testPerson) error { return nil },
+		func(ch chan int) error { return nil },
+	}
+	for _, fn := range invalid {
+		if err := c.Select("person", nil, "").Each(fn); err == nil {
+			t.Errorf("Each(%T): want error", fn)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"reflect"
@@ -11,26 +12,71 @@ type SelectContext struct {
 	err      error
 	sql      string
 	params   []interface{}
+	from     string // select ... from table，Chunk 中使用
+	where    string
 	db       *sql.DB
 	tx       *sql.Tx
+	source   *datasource // 不为空时从中选择从库
+	primary  bool        // 强制在主库上执行
+	dialect  Dialect
+	ctx      context.Context
 	step     int  // 构建过程步骤
 	advanced bool // search 模式
 	ordered  bool // 是否设置过order by
+	strict   bool // 严格映射模式
+	single   int  // 结果为结构体时的查询模式 singleFirst/singleOne
 }
 
-func createSelectContext(db *sql.DB, tx *sql.Tx, table string, columns []string, where string, params ...interface{}) *SelectContext {
+const (
+	singleFirst = iota + 1
+	singleOne
+)
+
+func createSelectContext(ctx context.Context, db *sql.DB, tx *sql.Tx, dialect Dialect, table string, columns []string, where string, params ...interface{}) *SelectContext {
 	var cs string
 	if len(columns) == 0 {
 		cs = " * "
 	} else {
 		cs = strings.Join(columns, ",")
 	}
-	sql := "select " + cs + " from " + table
+	from := "select " + cs + " from " + table
+	sql := from
 	where = strings.TrimSpace(where)
 	if where != "" {
 		sql += " where " + where
 	}
-	return &SelectContext{advanced: false, step: 1, sql: sql, params: params, db: db, tx: tx}
+	return &SelectContext{advanced: false, step: 1, sql: sql, from: from, where: where, params: params, db: db, tx: tx, dialect: dialect, ctx: ctx}
+}
+
+func (a *SelectContext) WithContext(ctx context.Context) *SelectContext {
+	a.ctx = ctx
+	return a
+}
+
+// 结果为结构体/结构体指针时，只取第一行，没有数据时返回 ErrNoRows
+// 结果为数组时不受影响
+func (a *SelectContext) First() *SelectContext {
+	a.single = singleFirst
+	return a
+}
+
+// 结果为结构体/结构体指针时，没有数据时返回 ErrNoRows，多于一行时返回 ErrMultipleRows，此时不会写入结果
+// 结果为数组时不受影响
+func (a *SelectContext) One() *SelectContext {
+	a.single = singleOne
+	return a
+}
+
+// 对当前查询开启严格映射模式，见 StrictMapping
+func (a *SelectContext) Strict() *SelectContext {
+	a.strict = true
+	return a
+}
+
+// 在主库上执行，用于写后立即读取的场景
+func (a *SelectContext) ForcePrimary() *SelectContext {
+	a.primary = true
+	return a
 }
 
 func (a *SelectContext) GroupBy(cols ...string) *SelectContext {
@@ -106,8 +152,9 @@ func (a *SelectContext) Limit(offset, size int) *SelectContext {
 		return a
 	}
 	a.step = 4
-	a.sql += " limit ?, ?"
-	a.params = append(a.params, offset, size)
+	limit, params := a.dialect.Limit(offset, size)
+	a.sql += limit
+	a.params = append(a.params, params...)
 	return a
 }
 
@@ -115,61 +162,106 @@ func (a *SelectContext) Result(r interface{}) error {
 	if a.err != nil { // 如果构建异常，不执行
 		return a.err
 	}
-	if reflect.TypeOf(r).Kind() != reflect.Ptr {
-		a.err = new(InvalidResultTypeError)
+	if err := checkResult(r); err != nil {
+		a.err = err
 		return a.err
 	}
-	val := reflect.ValueOf(r)    // pointer to T
-	ind := reflect.Indirect(val) // T
-	kind := ind.Kind()
-	if kind != reflect.Slice && kind != reflect.Struct && kind != reflect.Ptr {
-		a.err = new(InvalidResultTypeError)
-		return a.err
+	rows, err := a.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	_, slice, err := rows.writerOf(r)
+	if err != nil {
+		a.err = err
+		return err
+	}
+	single := a.single
+	if slice {
+		single = 0
+	}
+	dest := r
+	if single == singleOne { // 确认只有一行后再写入 r，多于一行时 r 保持不变
+		dest = reflect.New(reflect.TypeOf(r).Elem()).Interface()
+	}
+	count := 0
+	for rows.Next() {
+		count++
+		if single == singleOne && count > 1 {
+			return ErrMultipleRows
+		}
+		err = rows.Scan(dest)
+		if err != nil {
+			var me *MappingError
+			if errors.As(err, &me) {
+				a.err = err
+			}
+			return err
+		}
+		if single == singleFirst {
+			break
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if single != 0 && count == 0 {
+		return ErrNoRows
 	}
+	if single == singleOne {
+		reflect.ValueOf(r).Elem().Set(reflect.ValueOf(dest).Elem())
+	}
+	return nil
+}
+
+// 执行查询，返回逐行读取结果的迭代器，使用完毕后必须调用 Close
+func (a *SelectContext) Rows() (*Rows, error) {
+	if a.err != nil { // 如果构建异常，不执行
+		return nil, a.err
+	}
+	ctx := contextOf(a.ctx)
 	var stat *sql.Stmt
 	var err error
 	if a.tx == nil {
-		stat, err = a.db.Prepare(a.sql)
+		stat, err = a.queryDB().PrepareContext(ctx, rebind(a.dialect, a.sql))
 	} else {
-		stat, err = a.tx.Prepare(a.sql)
-	}
-	if stat != nil {
-		defer stat.Close()
+		stat, err = a.tx.PrepareContext(ctx, rebind(a.dialect, a.sql))
 	}
 	if err != nil {
-		return err
+		if stat != nil {
+			stat.Close()
+		}
+		return nil, wrapDBError(err)
 	}
 
 	var rows *sql.Rows
 	if a.params == nil || len(a.params) == 0 {
-		rows, err = stat.Query()
+		rows, err = stat.QueryContext(ctx)
 	} else {
-		rows, err = stat.Query(a.params...)
+		rows, err = stat.QueryContext(ctx, a.params...)
 	}
-	if rows != nil {
-		defer rows.Close()
+	if err != nil {
+		if rows != nil {
+			rows.Close()
+		}
+		stat.Close()
+		return nil, wrapDBError(err)
 	}
+	columns, err := rows.Columns()
 	if err != nil {
-		return err
+		rows.Close()
+		stat.Close()
+		return nil, err
 	}
-	for rows.Next() {
-		columns, err := rows.Columns()
-		if err != nil {
-			return err
-		}
-		res := make([]interface{}, len(columns))
-		v := make([]interface{}, len(columns))
-		for i := range v {
-			res[i] = &v[i]
-		}
-		rows.Scan(res...)
-		err = writeValue(columns, v, r)
-		if err != nil {
-			a.err = err
-			return err
-		}
+	return newRows(stat, rows, columns, a.strict || strictMapping.Load()), nil
+}
+
+// 执行查询的连接池，事务中不使用
+func (a *SelectContext) queryDB() *sql.DB {
+	if a.primary || a.source == nil {
+		return a.db
 	}
-	return nil
+	return a.source.replica()
 }
 
 // 返回 SelectContext 构建过程中的异常
@@ -179,5 +271,5 @@ func (a *SelectContext) ContextError() error {
 
 // 返回语句和参数
 func (a *SelectContext) Desc() (string, []interface{}) {
-	return a.sql, a.params
+	return rebind(a.dialect, a.sql), a.params
 }
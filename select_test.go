@@ -0,0 +1,130 @@
+package orm
+
+import (
+	"errors"
+	"testing"
+)
+
+// 插入 names 对应的行，主键从 1 开始
+func insertNames(t *testing.T, c *Context, names ...string) {
+	t.Helper()
+	ps := make([]testPerson, len(names))
+	for i, n := range names {
+		ps[i] = testPerson{Name: n, Age: i + 1}
+	}
+	if _, err := c.Insert("person", []string{"name", "age"}, ps).Exec(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOne(t *testing.T) {
+	c := openTestContext(t)
+	insertNames(t, c, "a", "b")
+	var p testPerson
+	if err := c.Select("person", []string{"id", "name"}, "name = ?", "b").One().Result(&p); err != nil || p.Name != "b" {
+		t.Fatalf("One() = %+v, %v", p, err)
+	}
+	var pp *testPerson
+	if err := c.Select("person", []string{"id", "name"}, "name = ?", "a").One().Result(&pp); err != nil || pp == nil || pp.Name != "a" {
+		t.Fatalf("One() into pointer = %+v, %v", pp, err)
+	}
+	if err := c.Select("person", []string{"id", "name"}, "name = ?", "c").One().Result(&p); !errors.Is(err, ErrNoRows) {
+		t.Fatalf("err = %v, want ErrNoRows", err)
+	}
+	// 多于一行时 r 保持不变
+	p = testPerson{Name: "unchanged"}
+	if err := c.Select("person", []string{"id", "name"}, "").One().Result(&p); !errors.Is(err, ErrMultipleRows) {
+		t.Fatalf("err = %v, want ErrMultipleRows", err)
+	}
+	if p.Name != "unchanged" || p.ID != 0 {
+		t.Fatalf("result changed to %+v", p)
+	}
+	// 数组不受影响
+	var ps []testPerson
+	if err := c.Select("person", []string{"id"}, "").One().Result(&ps); err != nil || len(ps) != 2 {
+		t.Fatalf("One() into slice = %d rows, %v", len(ps), err)
+	}
+	ps = nil
+	if err := c.Select("person", []string{"id"}, "id > 10").One().Result(&ps); err != nil || len(ps) != 0 {
+		t.Fatalf("One() into empty slice = %d rows, %v", len(ps), err)
+	}
+}
+
+func TestFirst(t *testing.T) {
+	c := openTestContext(t)
+	insertNames(t, c, "a", "b")
+	var p testPerson
+	if err := c.Select("person", []string{"id", "name"}, "").OrderByDesc("id").First().Result(&p); err != nil || p.Name != "b" {
+		t.Fatalf("First() = %+v, %v", p, err)
+	}
+	if err := c.Select("person", []string{"id", "name"}, "id > 10").First().Result(&p); !errors.Is(err, ErrNoRows) {
+		t.Fatalf("err = %v, want ErrNoRows", err)
+	}
+}
+
+func TestResultScalar(t *testing.T) {
+	c := openTestContext(t)
+	insertNames(t, c, "a", "b", "c")
+	var count int64
+	if err := c.Select("person", []string{"count(1)"}, "").Result(&count); err != nil || count != 3 {
+		t.Fatalf("count = %d, %v", count, err)
+	}
+	var name string
+	if err := c.Select("person", []string{"name"}, "id = ?", 2).Result(&name); err != nil || name != "b" {
+		t.Fatalf("name = %s, %v", name, err)
+	}
+	var age *int
+	if err := c.Select("person", []string{"age"}, "id = ?", 3).Result(&age); err != nil || age == nil || *age != 3 {
+		t.Fatalf("age = %v, %v", age, err)
+	}
+	var names []string
+	if err := c.Select("person", []string{"name"}, "").OrderByDesc("id").Result(&names); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 3 || names[0] != "c" || names[2] != "a" {
+		t.Fatalf("names = %v", names)
+	}
+	var nicks []*string // null 写入 nil
+	if err := c.Select("person", []string{"nick"}, "").Result(&nicks); err != nil || len(nicks) != 3 || nicks[0] != nil {
+		t.Fatalf("nicks = %v, %v", nicks, err)
+	}
+	// 多列时取第一列，严格模式下返回异常
+	var ids []int64
+	if err := c.Select("person", []string{"id", "name"}, "").OrderByAsc("id").Result(&ids); err != nil || len(ids) != 3 || ids[0] != 1 {
+		t.Fatalf("ids = %v, %v", ids, err)
+	}
+	if err := c.Select("person", []string{"id", "name"}, "").Strict().Result(&ids); !errors.Is(err, ErrUnmappedColumn) {
+		t.Fatalf("err = %v, want ErrUnmappedColumn", err)
+	}
+}
+
+func TestResultMap(t *testing.T) {
+	c := openTestContext(t)
+	insertNames(t, c, "a", "b")
+	var m map[string]interface{}
+	if err := c.Select("person", []string{"id", "name", "nick"}, "id = ?", 1).Result(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m["id"] != int64(1) || m["name"] != "a" || m["nick"] != nil {
+		t.Fatalf("map = %v", m)
+	}
+	var ms []map[string]interface{}
+	if err := c.Select("person", []string{"id", "age"}, "").OrderByAsc("id").Result(&ms); err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 || ms[1]["age"] != int64(2) {
+		t.Fatalf("maps = %v", ms)
+	}
+}
+
+func TestResultInvalidType(t *testing.T) {
+	c := openTestContext(t)
+	var p testPerson
+	if err := c.Select("person", nil, "").Result(p); err == nil {
+		t.Fatal("want error for non-pointer result")
+	}
+	var ch chan int
+	if err := c.Select("person", nil, "").Result(&ch); err == nil {
+		t.Fatal("want error for chan result")
+	}
+}
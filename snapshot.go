@@ -0,0 +1,167 @@
+package orm
+
+import (
+	"errors"
+	"reflect"
+)
+
+// 嵌入到结构体中(不能是指针)，通过 Result 查询时记录查询到的字段的值
+// 之后可以通过 UpdateChanged 只更新修改过的字段，如
+//
+//	type Person struct {
+//		orm.Snapshot
+//		ID   int64  `column:"id,pk"`
+//		Name string `column:"user_name"`
+//	}
+//
+//	err := ctx.FindByPK(&p, 1)
+//	p.Name = "new name"
+//	_, err = ctx.UpdateChanged(&p).Exec() // update person set user_name = ? where id = ?
+type Snapshot struct {
+	values map[string]interface{} // column -> 查询时的值
+}
+
+var type_snapshot = reflect.TypeOf(Snapshot{})
+
+// 记录 v 中 fields 的值，fields 中可能有 nil
+func takeSnapshot(v reflect.Value, fields []*fieldMeta) Snapshot {
+	values := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		if f == nil || f.decode == nil {
+			continue
+		}
+		values[f.column] = snapshotValue(v, f)
+	}
+	return Snapshot{values: values}
+}
+
+// 字段转换为参数后的值的副本，字段被原地修改(如 *p.Nick = "x", p.Tags[0] = "x")后快照保持不变
+func snapshotValue(v reflect.Value, f *fieldMeta) interface{} {
+	fv, ok := fieldByIndex(v, f.index)
+	if !ok {
+		return nil
+	}
+	if f.encode != nil {
+		if ev, err := f.encode(fv); err == nil {
+			return snapshotCopy(reflect.ValueOf(ev))
+		}
+	}
+	return snapshotCopy(fv)
+}
+
+// 深度复制 v，指针保存指向的值，slice/map 逐个元素复制
+func snapshotCopy(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return snapshotCopy(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if b, ok := v.Interface().([]byte); ok {
+			return append([]byte{}, b...)
+		}
+		rs := make([]interface{}, v.Len())
+		for i := range rs {
+			rs[i] = snapshotCopy(v.Index(i))
+		}
+		return rs
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		rs := make(map[interface{}]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			rs[iter.Key().Interface()] = snapshotCopy(iter.Value())
+		}
+		return rs
+	}
+	return v.Interface()
+}
+
+// 修改过的字段，按字段定义的顺序，不包括主键
+func (a *structMeta) changedColumns(v reflect.Value, s Snapshot) []string {
+	var cols []string
+	for _, f := range a.fields {
+		old, ok := s.values[f.column]
+		if !ok || f.column == a.pk {
+			continue
+		}
+		if !reflect.DeepEqual(old, snapshotValue(v, f)) {
+			cols = append(cols, f.column)
+		}
+	}
+	return cols
+}
+
+// 按主键更新，主键必须在查询的字段中且未被修改，否则会更新其他行
+func (a *structMeta) checkPK(v reflect.Value, s Snapshot) error {
+	f, ok := a.columns[a.pk]
+	if !ok {
+		return errors.New("can not find primary key of " + a.typ.String() + ", add option pk to tag(" + tag + ")")
+	}
+	old, ok := s.values[a.pk]
+	if !ok {
+		return errors.New("primary key " + a.pk + " of " + a.typ.String() + " was not selected")
+	}
+	if !reflect.DeepEqual(old, snapshotValue(v, f)) {
+		return errors.New("primary key " + a.pk + " of " + a.typ.String() + " was changed after loading")
+	}
+	return nil
+}
+
+func updateChanged(b builder, data interface{}) *UpdateContext {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return &UpdateContext{err: errors.New("UpdateChanged requires pointer of struct")}
+	}
+	v = v.Elem()
+	meta := metaOf(v.Type())
+	if meta.snapshot == nil {
+		return &UpdateContext{err: errors.New(v.Type().String() + " does not embed orm.Snapshot")}
+	}
+	snapshot := fieldByIndexAlloc(v, meta.snapshot).Addr().Interface().(*Snapshot)
+	if snapshot.values == nil {
+		return &UpdateContext{err: errors.New("no snapshot of " + v.Type().String() + ", load it by Result first")}
+	}
+	if err := meta.checkPK(v, *snapshot); err != nil {
+		return &UpdateContext{err: err}
+	}
+	cols := meta.changedColumns(v, *snapshot)
+	if len(cols) == 0 {
+		return &UpdateContext{skip: true}
+	}
+	c := updateModel(b, data, cols...)
+	if c.err != nil {
+		return c
+	}
+	c.onSuccess = func() { // 更新成功后以当前的值作为快照
+		values := make(map[string]interface{}, len(snapshot.values))
+		for k, e := range snapshot.values {
+			values[k] = e
+		}
+		for _, col := range cols {
+			values[col] = snapshotValue(v, meta.columns[col])
+		}
+		snapshot.values = values
+	}
+	return c
+}
+
+// 只更新通过 Result 查询后修改过的字段，data 必须是嵌入了 Snapshot 的结构体指针
+// 只比较查询时包含的字段，未查询的字段即使修改了也不会更新；主键必须在查询的字段中且不能修改
+// 没有修改过的字段时不执行，Exec 返回 0，执行成功后以当前的值作为新的快照
+func (a *Context) UpdateChanged(data interface{}) *UpdateContext {
+	return updateChanged(a, data)
+}
+
+func (a *TransactionContext) UpdateChanged(data interface{}) *UpdateContext {
+	return updateChanged(a, data)
+}
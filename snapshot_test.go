@@ -0,0 +1,126 @@
+package orm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type snapshotPerson struct {
+	Snapshot
+	ID       int64      `column:"id,pk"`
+	Name     string     `column:"name"`
+	Nick     *string    `column:"nick"`
+	Birthday *time.Time `column:"birthday"`
+}
+
+func (snapshotPerson) TableName() string { return "person" }
+
+func loadSnapshotPerson(t *testing.T, c *Context) *snapshotPerson {
+	t.Helper()
+	nick := "n"
+	birthday := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := c.InsertModel(&snapshotPerson{Name: "a", Nick: &nick, Birthday: &birthday}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	var p snapshotPerson
+	if err := c.FindByPK(&p, 1); err != nil {
+		t.Fatal(err)
+	}
+	return &p
+}
+
+func TestUpdateChanged(t *testing.T) {
+	c := openTestContext(t)
+	p := loadSnapshotPerson(t, c)
+	if n, err := c.UpdateChanged(p).Exec(); err != nil || n != 0 {
+		t.Fatalf("unchanged Exec() = %d, %v", n, err)
+	}
+	p.Name = "b"
+	sql, _ := c.UpdateChanged(p).Desc()
+	if sql != `update "person" set "name" = ? where "id" = ?` {
+		t.Fatalf("sql = %s", sql)
+	}
+	if n, err := c.UpdateChanged(p).Exec(); err != nil || n != 1 {
+		t.Fatalf("Exec() = %d, %v", n, err)
+	}
+	if sql, _ := c.UpdateChanged(p).Desc(); sql != "" {
+		t.Fatalf("snapshot not refreshed, sql = %s", sql)
+	}
+}
+
+func TestUpdateChangedPointerFields(t *testing.T) {
+	c := openTestContext(t)
+	p := loadSnapshotPerson(t, c)
+	*p.Nick = "changed"
+	*p.Birthday = p.Birthday.Add(time.Hour)
+	sql, params := c.UpdateChanged(p).Desc()
+	if sql != `update "person" set "nick" = ?,"birthday" = ? where "id" = ?` {
+		t.Fatalf("sql = %s", sql)
+	}
+	if len(params) != 3 {
+		t.Fatalf("params = %v", params)
+	}
+	p.Nick = nil
+	if sql, _ := c.UpdateChanged(p).Desc(); !strings.Contains(sql, `"nick" = ?`) {
+		t.Fatalf("nil pointer not detected, sql = %s", sql)
+	}
+}
+
+func TestSnapshotCopy(t *testing.T) {
+	tags := []string{"a"}
+	m := map[string][]int{"x": {1}}
+	b := []byte("b")
+	st, sm, sb := snapshotCopy(reflect.ValueOf(tags)), snapshotCopy(reflect.ValueOf(m)), snapshotCopy(reflect.ValueOf(b))
+	if !reflect.DeepEqual(st, snapshotCopy(reflect.ValueOf(tags))) {
+		t.Fatal("copies of the same value differ")
+	}
+	tags[0], m["x"][0], b[0] = "z", 2, 'z'
+	if reflect.DeepEqual(st, snapshotCopy(reflect.ValueOf(tags))) ||
+		reflect.DeepEqual(sm, snapshotCopy(reflect.ValueOf(m))) ||
+		reflect.DeepEqual(sb, snapshotCopy(reflect.ValueOf(b))) {
+		t.Fatal("snapshot changed with the field")
+	}
+}
+
+// 主键被修改后不能按当前的主键更新其他行
+func TestUpdateChangedPK(t *testing.T) {
+	c := openTestContext(t)
+	p := loadSnapshotPerson(t, c)
+	if _, err := c.InsertModel(&snapshotPerson{Name: "other"}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	p.ID, p.Name = 2, "b"
+	if _, err := c.UpdateChanged(p).Exec(); err == nil {
+		t.Fatal("want error for changed primary key")
+	}
+	var q snapshotPerson
+	if err := c.FindByPK(&q, 2); err != nil || q.Name != "other" {
+		t.Fatalf("row 2 = %+v, %v", q, err)
+	}
+	// 没有查询主键
+	var r snapshotPerson
+	if err := c.Select("person", []string{"name"}, "id = ?", 1).One().Result(&r); err != nil {
+		t.Fatal(err)
+	}
+	r.ID, r.Name = 1, "c"
+	if _, err := c.UpdateChanged(&r).Exec(); err == nil {
+		t.Fatal("want error when the primary key was not selected")
+	}
+}
+
+// 只比较查询时包含的字段
+func TestUpdateChangedUnselected(t *testing.T) {
+	c := openTestContext(t)
+	loadSnapshotPerson(t, c)
+	var p snapshotPerson
+	if err := c.Select("person", []string{"id", "name"}, "id = ?", 1).One().Result(&p); err != nil {
+		t.Fatal(err)
+	}
+	nick := "x"
+	p.Name, p.Nick = "b", &nick
+	if sql, _ := c.UpdateChanged(&p).Desc(); sql != `update "person" set "name" = ? where "id" = ?` {
+		t.Fatalf("sql = %s", sql)
+	}
+}
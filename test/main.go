@@ -25,7 +25,10 @@ update_time datetime
 func init() {
 	dsName := "default"
 	ds := fmt.Sprintf("%s:%s@tcp(127.0.0.1:3306)/test", "", "")
-	orm.RegisterDatsource(orm.NewDatasourceConfig(dsName, ds).MaxConn(10).MaxIdleConn(3))
+	err := orm.RegisterDatasource(orm.NewDatasourceConfig(dsName, ds).MaxConn(10).MaxIdleConn(3))
+	if err != nil {
+		panic(err)
+	}
 }
 
 type Person struct {
@@ -37,10 +40,6 @@ type Person struct {
 	UpdateTime time.Time `column:"update_time"`
 }
 
-type CountResult struct {
-	Count int `column:"cnt"`
-}
-
 type AgeRangeResult struct {
 	AgeRange string `column:"age_range"`
 	Count    int    `column:"cnt"`
@@ -65,7 +64,7 @@ func (a *TableHandler) Insert(data ...*Person) (int64, error) {
 
 func (a *TableHandler) SelectById(id int64) (Person, error) {
 	var r Person
-	err := orm.CreateContext().Select(a.tb, a.fullCols, "id=?", id).Result(&r)
+	err := orm.CreateContext().Select(a.tb, a.fullCols, "id=?", id).One().Result(&r)
 	return r, err
 }
 
@@ -78,9 +77,9 @@ func (a *TableHandler) Delete(condition string, params ...interface{}) (int64, e
 }
 
 func (a *TableHandler) Page(condition string, params []interface{}, offset, limit int) (int, []Person, error) {
-	var cnt CountResult
+	var cnt int
 	var rs []Person
-	err := orm.CreateContext().Select(a.tb, []string{"count(1) as cnt"}, condition, params...).Result(&cnt)
+	err := orm.CreateContext().Select(a.tb, []string{"count(1)"}, condition, params...).Result(&cnt)
 	if err != nil {
 		return 0, nil, err
 	}
@@ -88,7 +87,7 @@ func (a *TableHandler) Page(condition string, params []interface{}, offset, limi
 	if err != nil {
 		return 0, nil, err
 	}
-	return cnt.Count, rs, nil
+	return cnt, rs, nil
 }
 
 func (a *TableHandler) Select(condition string, params ...interface{}) ([]Person, error) {
@@ -143,6 +142,7 @@ func (a *TableHandler) Transaction() error {
 }
 
 func main() {
+	defer orm.CloseAll()
 	h := TableHandler{}
 	h.Init()
 	now := time.Now()
@@ -1,31 +1,54 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 )
 
 type TransactionContext struct {
-	tx *sql.Tx
+	tx      *sql.Tx
+	dialect Dialect
+	ctx     context.Context
 }
 
 func (a *TransactionContext) Insert(table string, columns []string, dataset interface{}) *InsertContext {
-	return createInsertContext(nil, a.tx, table, columns, interfaceToArray(dataset)...)
+	return createInsertContext(a.ctx, nil, a.tx, a.dialect, table, columns, dataset)
+}
+
+func (a *TransactionContext) InsertFrom(table string, columns []string, query *SelectContext) *InsertContext {
+	return createInsertFromContext(a.ctx, nil, a.tx, a.dialect, table, columns, query)
 }
 
 func (a *TransactionContext) Delete(table string, where string) *DeleteContext {
-	return createDeleteContext(nil, a.tx, table, where)
+	return createDeleteContext(a.ctx, nil, a.tx, a.dialect, table, where)
 }
 
 func (a *TransactionContext) Update(table string, setCols []string, where string) *UpdateContext {
-	return createUpdateContext(nil, a.tx, table, setCols, where)
+	return createUpdateContext(a.ctx, nil, a.tx, a.dialect, table, setCols, where)
+}
+
+func (a *TransactionContext) UpdateBatch(table string, setCols, keyCols []string, dataset interface{}) *BatchUpdateContext {
+	return createBatchUpdateContext(a.ctx, nil, a.tx, a.dialect, table, setCols, keyCols, dataset)
 }
 
 func (a *TransactionContext) Select(table string, columns []string, where string, params ...interface{}) *SelectContext {
-	return createSelectContext(nil, a.tx, table, columns, where, params...)
+	return createSelectContext(a.ctx, nil, a.tx, a.dialect, table, columns, where, params...)
 }
 
 func (a *TransactionContext) Search(sql string, params ...interface{}) *SelectContext {
-	return &SelectContext{advanced: true, sql: sql, params: params, tx: a.tx}
+	return &SelectContext{advanced: true, sql: sql, params: params, tx: a.tx, dialect: a.dialect, ctx: a.ctx}
+}
+
+// 之后创建的 Insert/Delete/Update/Select/Search 均使用 ctx
+func (a *TransactionContext) WithContext(ctx context.Context) *TransactionContext {
+	c := *a
+	c.ctx = ctx
+	return &c
+}
+
+// 当前数据源的方言
+func (a *TransactionContext) Dialect() Dialect {
+	return a.dialect
 }
 
 func (a *TransactionContext) Rollback() error {
@@ -33,5 +56,5 @@ func (a *TransactionContext) Rollback() error {
 }
 
 func (a *TransactionContext) Commit() error {
-	return a.tx.Commit()
+	return wrapDBError(a.tx.Commit())
 }
@@ -1,46 +1,74 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"strings"
 )
 
 type UpdateContext struct {
 	err       error
-	sql       string
+	table     string
+	where     string
 	setCols   []string
+	sets      []setClause // 通过 Set 设置的字段
 	whereCols []string
 	build     bool
+	skip      bool   // 没有需要更新的字段，不执行
+	onSuccess func() // 执行成功后调用
 	params    []interface{}
 	db        *sql.DB
 	tx        *sql.Tx
+	dialect   Dialect
+	ctx       context.Context
 }
 
-func createUpdateContext(db *sql.DB, tx *sql.Tx, table string, setCols []string, where string) *UpdateContext {
-	if len(setCols) == 0 {
-		ctx := &UpdateContext{build: false, err: errors.New(`no [set] columns to update`)}
-		return ctx
-	}
+type setClause struct {
+	column string
+	value  interface{}
+}
+
+// 原生的 sql 表达式，作为 UpdateContext.Set 的值时直接写入语句，如
+//
+//	Set("counter", orm.Expr("counter + ?", 1))
+//	Set("update_time", orm.Expr("now()"))
+type Expression struct {
+	sql  string
+	args []interface{}
+}
+
+func Expr(sql string, args ...interface{}) Expression {
+	return Expression{sql: sql, args: args}
+}
+
+// setCols 可以为空，此时必须通过 Set 设置需要更新的字段
+func createUpdateContext(ctx context.Context, db *sql.DB, tx *sql.Tx, dialect Dialect, table string, setCols []string, where string) *UpdateContext {
 	where = strings.TrimSpace(where)
 	if where == "" {
-		ctx := &UpdateContext{build: false, err: errors.New(`for security. can't update without [where] parameter. to update all dataset, pass "1=1" to [where] parameter`)}
-		return ctx
+		return &UpdateContext{build: false, err: fmt.Errorf(`%w. for security. can't update without [where] parameter. to update all dataset, pass "1=1" to [where] parameter`, ErrMissingWhere)}
 	}
+	return &UpdateContext{build: false, db: db, tx: tx, dialect: dialect, ctx: ctx, table: table, where: where, setCols: setCols}
+}
 
-	var sb strings.Builder
-	for i, e := range setCols {
-		if i > 0 {
-			sb.WriteString(",")
-		}
-		sb.WriteString(e + " = ?")
-	}
-	sql := "update " + table + " set " + sb.String() + " where " + where
+func (a *UpdateContext) WithContext(ctx context.Context) *UpdateContext {
+	a.ctx = ctx
+	return a
+}
 
-	return &UpdateContext{build: false, db: db, tx: tx, sql: sql, setCols: setCols}
+// 设置字段的值，value 可以是 Expr 构建的表达式，在 setCols 之后生成
+// 参数位于 setCols 的参数与 where 的参数之间，不影响 Params/ReflectParamsFrom 的参数
+// 没有 setCols 时 Set 即完成构建，where 中没有参数时不需要调用 Params
+func (a *UpdateContext) Set(column string, value interface{}) *UpdateContext {
+	a.sets = append(a.sets, setClause{column: column, value: value})
+	if len(a.setCols) == 0 {
+		a.build = true
+	}
+	return a
 }
 
-// 直接传递所有参数
+// 直接传递所有参数，依次为 setCols 及 where 的参数
 func (a *UpdateContext) Params(params ...interface{}) *UpdateContext {
 	a.params = params
 	a.build = true
@@ -62,28 +90,29 @@ func (a *UpdateContext) ReflectParamsFrom(data interface{}, whereCols []string)
 	return a
 }
 
-func execute(db *sql.DB, tx *sql.Tx, updelSQL string, params ...interface{}) (int64, error) {
+func execute(ctx context.Context, db *sql.DB, tx *sql.Tx, dialect Dialect, updelSQL string, params ...interface{}) (int64, error) {
+	ctx = contextOf(ctx)
 	var stat *sql.Stmt
 	var err error
 	if tx == nil {
-		stat, err = db.Prepare(updelSQL)
+		stat, err = db.PrepareContext(ctx, rebind(dialect, updelSQL))
 	} else {
-		stat, err = tx.Prepare(updelSQL)
+		stat, err = tx.PrepareContext(ctx, rebind(dialect, updelSQL))
 	}
 	if stat != nil {
 		defer stat.Close()
 	}
 	if err != nil {
-		return 0, err
+		return 0, wrapDBError(err)
 	}
 	var rs sql.Result
 	if len(params) == 0 {
-		rs, err = stat.Exec()
+		rs, err = stat.ExecContext(ctx)
 	} else {
-		rs, err = stat.Exec(params...)
+		rs, err = stat.ExecContext(ctx, params...)
 	}
 	if err != nil {
-		return 0, err
+		return 0, wrapDBError(err)
 	}
 	return rs.RowsAffected()
 }
@@ -92,13 +121,46 @@ func (a *UpdateContext) Exec() (int64, error) {
 	if a.err != nil { // 如果构建异常，不执行
 		return 0, a.err
 	}
+	if a.skip {
+		return 0, nil
+	}
 	if !a.build {
 		return 0, errors.New("build failed because of no params passed")
 	}
-	if a.sql == "" {
-		return 0, nil
+	if len(a.setCols) == 0 && len(a.sets) == 0 {
+		return 0, ErrNoSetColumns
+	}
+	sql, params := a.statement()
+	n, err := execute(a.ctx, a.db, a.tx, a.dialect, sql, params...)
+	if err == nil && a.onSuccess != nil {
+		a.onSuccess()
+	}
+	return n, err
+}
+
+// 语句及参数，Set 的参数位于 setCols 与 where 的参数之间
+func (a *UpdateContext) statement() (string, []interface{}) {
+	n := len(a.setCols)
+	if n > len(a.params) {
+		n = len(a.params)
+	}
+	sets := make([]string, 0, len(a.setCols)+len(a.sets))
+	params := make([]interface{}, 0, len(a.params)+len(a.sets))
+	params = append(params, a.params[:n]...)
+	for _, c := range a.setCols {
+		sets = append(sets, quoteIdentifier(a.dialect, c)+" = ?")
 	}
-	return execute(a.db, a.tx, a.sql, a.params...)
+	for _, c := range a.sets {
+		if e, ok := c.value.(Expression); ok {
+			sets = append(sets, quoteIdentifier(a.dialect, c.column)+" = "+e.sql)
+			params = append(params, e.args...)
+		} else {
+			sets = append(sets, quoteIdentifier(a.dialect, c.column)+" = ?")
+			params = append(params, c.value)
+		}
+	}
+	params = append(params, a.params[n:]...)
+	return "update " + a.table + " set " + strings.Join(sets, ",") + " where " + a.where, params
 }
 
 // 返回 UpdateContext 构建过程中的异常
@@ -108,5 +170,9 @@ func (a *UpdateContext) ContextError() error {
 
 // 返回语句和参数
 func (a *UpdateContext) Desc() (string, []interface{}) {
-	return a.sql, a.params
+	if a.err != nil || a.skip {
+		return "", a.params
+	}
+	sql, params := a.statement()
+	return rebind(a.dialect, sql), params
 }
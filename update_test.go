@@ -0,0 +1,41 @@
+package orm
+
+import "testing"
+
+func TestUpdateSetExpr(t *testing.T) {
+	c := openTestContext(t)
+	if _, err := c.Insert("person", []string{"name", "age"}, []testPerson{{Name: "a", Age: 1}, {Name: "b", Age: 2}}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	n, err := c.Update("person", nil, "1=1").Set("name", Expr("name || ?", "x")).Exec()
+	if err != nil || n != 2 {
+		t.Fatalf("Exec() = %d, %v", n, err)
+	}
+	n, err = c.Update("person", []string{"name"}, "id = ?").Set("age", Expr("age + ?", 10)).Params("c", 1).Exec()
+	if err != nil || n != 1 {
+		t.Fatalf("Exec() = %d, %v", n, err)
+	}
+	var rs []testPerson
+	if err := c.Select("person", []string{"id", "name", "age"}, "").OrderByAsc("id").Result(&rs); err != nil {
+		t.Fatal(err)
+	}
+	if rs[0].Name != "c" || rs[0].Age != 11 || rs[1].Name != "bx" || rs[1].Age != 2 {
+		t.Fatalf("rows = %+v", rs)
+	}
+}
+
+func TestUpdateStatement(t *testing.T) {
+	u := createUpdateContext(nil, nil, nil, PostgreSQL, "person", []string{"name"}, "id = ?").
+		Set("age", Expr("age + ?", 1)).Set("nick", "n").Params("a", 7)
+	sql, params := u.Desc()
+	want := `update person set "name" = $1,"age" = age + $2,"nick" = $3 where id = $4`
+	if sql != want {
+		t.Fatalf("sql = %s, want %s", sql, want)
+	}
+	if len(params) != 4 || params[0] != "a" || params[1] != 1 || params[2] != "n" || params[3] != 7 {
+		t.Fatalf("params = %v", params)
+	}
+	if _, err := createUpdateContext(nil, nil, nil, MySQL, "person", nil, "id = 1").Params().Exec(); err != ErrNoSetColumns {
+		t.Fatalf("err = %v, want ErrNoSetColumns", err)
+	}
+}
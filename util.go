@@ -1,10 +1,5 @@
 package orm
 
-import (
-	"reflect"
-	"strings"
-)
-
 // 返回所有的column字段，除了 excepts
 // t 可以是 struct 也可以是 *struct
 func ColumnsExcept(t interface{}, excepts ...string) []string {
@@ -13,25 +8,15 @@ func ColumnsExcept(t interface{}, excepts ...string) []string {
 		m[excepts[i]] = 1
 	}
 	rs := make([]string, 0)
-	tp := reflect.TypeOf(t)
-	if tp.Kind() == reflect.Struct {
-		p := reflect.PtrTo(reflect.TypeOf(t))
-		for i := 0; i < p.Elem().NumField(); i++ {
-			column := strings.TrimSpace(p.Elem().Field(i).Tag.Get(tag))
-			if _, ok := m[column]; ok {
-				continue
-			}
-			rs = append(rs, column)
-		}
-	} else if tp.Kind() == reflect.Ptr {
-		p := reflect.TypeOf(t)
-		for i := 0; i < p.Elem().NumField(); i++ {
-			column := strings.TrimSpace(p.Elem().Field(i).Tag.Get(tag))
-			if _, ok := m[column]; ok {
-				continue
-			}
-			rs = append(rs, column)
+	meta := metaOfValue(t)
+	if meta == nil {
+		return rs
+	}
+	for _, f := range meta.fields {
+		if _, ok := m[f.column]; ok {
+			continue
 		}
+		rs = append(rs, f.column)
 	}
 	return rs
 }
@@ -40,18 +25,13 @@ func ColumnsExcept(t interface{}, excepts ...string) []string {
 // t 必须是 strcut 或者 指向 strcut 的指针
 func FieldMapping(t interface{}) map[string]string {
 	fn := make(map[string]string)
-	tp := reflect.TypeOf(t)
-	if tp.Kind() == reflect.Struct {
-		p := reflect.PtrTo(reflect.TypeOf(t))
-		for i := 0; i < p.Elem().NumField(); i++ {
-			column := strings.TrimSpace(p.Elem().Field(i).Tag.Get(tag))
-			fn[column] = p.Elem().Field(i).Name
-		}
-	} else if tp.Kind() == reflect.Ptr {
-		p := reflect.TypeOf(t)
-		for i := 0; i < p.Elem().NumField(); i++ {
-			column := strings.TrimSpace(p.Elem().Field(i).Tag.Get(tag))
-			fn[column] = p.Elem().Field(i).Name
+	meta := metaOfValue(t)
+	if meta == nil {
+		return fn
+	}
+	for _, f := range meta.fields {
+		if _, ok := fn[f.column]; !ok {
+			fn[f.column] = f.name
 		}
 	}
 	return fn